@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package update
+
+import (
+	"errors"
+	"os"
+)
+
+// chown restores file ownership from a tar header. It is a no-op on
+// Windows, which has no uid/gid concept.
+//
+// Archives built by CI/release tooling commonly carry a Uid/Gid that
+// doesn't match the uid of a non-privileged installer process (0, or the
+// builder's own uid); os.Chown to a mismatched uid/gid fails with
+// EPERM/EACCES by standard POSIX semantics, so that case is ignored rather
+// than aborting the whole extraction.
+func chown(path string, uid, gid int) error {
+	if err := os.Chown(path, uid, gid); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
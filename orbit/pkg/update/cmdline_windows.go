@@ -0,0 +1,115 @@
+//go:build windows
+// +build windows
+
+package update
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitCommandLine splits a Windows command line (as returned by SCM's
+// BinaryPathName) into its executable path and arguments, honoring a
+// quoted path containing spaces (e.g. `"C:\Program Files\Fleet\orbit.exe" --foo bar`).
+// This follows the same quoting rules as the Windows CommandLineToArgvW API.
+func splitCommandLine(cmdLine string) (string, []string, error) {
+	args, err := splitArgs(cmdLine)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("empty command line")
+	}
+	return args[0], args[1:], nil
+}
+
+// joinCommandLine builds a Windows command line from path and args, quoting
+// path if it contains spaces so it round-trips through splitCommandLine.
+func joinCommandLine(path string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, quoteArg(path))
+	for _, a := range args {
+		parts = append(parts, quoteArg(a))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, r := range s {
+		switch r {
+		case '\\':
+			slashes++
+			b.WriteRune(r)
+		case '"':
+			for i := 0; i < slashes+1; i++ {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+			slashes = 0
+		default:
+			slashes = 0
+			b.WriteRune(r)
+		}
+	}
+	for i := 0; i < slashes; i++ {
+		b.WriteByte('\\')
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// splitArgs tokenizes a Windows-style command line, handling a double-quoted
+// argument (including one containing spaces) as a single token.
+func splitArgs(cmdLine string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inQuotes := false
+	hasToken := false
+
+	runes := []rune(cmdLine)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			j := i
+			for j < len(runes) && runes[j] == '\\' {
+				j++
+			}
+			n := j - i
+			if j < len(runes) && runes[j] == '"' {
+				cur.WriteString(strings.Repeat(`\`, n/2) + strings.Repeat(`"`, n%2))
+				inQuotes = inQuotes != (n%2 == 0)
+			} else {
+				cur.WriteString(strings.Repeat(`\`, n))
+				j--
+			}
+			i = j
+			hasToken = true
+		case r == '"':
+			inQuotes = !inQuotes
+			hasToken = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if hasToken {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasToken = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasToken = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quoted string in command line: %q", cmdLine)
+	}
+	if hasToken {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
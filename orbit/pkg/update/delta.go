@@ -0,0 +1,151 @@
+package update
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/pkg/secure"
+	"github.com/gabstv/go-bsdiff/pkg/bspatch"
+	"github.com/rs/zerolog/log"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// Delta target naming convention
+//
+// For every full target `<target>/<platform>/<channel>/<TargetFile>` the
+// repository may optionally publish a sibling patch target named:
+//
+//	<target>/<platform>/<channel>/<TargetFile>.<oldsha256>.bsdiff
+//
+// where <oldsha256> is the lowercase hex sha256 of the previously published
+// full target that the patch was generated from. A client that currently
+// has a file matching <oldsha256> installed can apply the patch to produce
+// the new target instead of downloading it in full. The format of a
+// `<TargetFile>.deltas.json` manifest listing several patches is described
+// by deltaManifestEntry, but tryDeltaDownload does not read such a
+// manifest: it only looks up the `.<oldsha256>.bsdiff` sibling target.
+
+// deltaManifestEntry describes one available patch in a
+// `<TargetFile>.deltas.json` manifest.
+type deltaManifestEntry struct {
+	FromSHA256  string `json:"from_sha256"`
+	PatchPath   string `json:"patch_path"`
+	PatchLen    int64  `json:"patch_length"`
+	PatchSHA256 string `json:"patch_sha256"`
+}
+
+// tryDeltaDownload attempts to reconstruct the target at localPath by
+// downloading and applying a bsdiff patch against the currently installed
+// file, writing the result into tmp. It returns true if the delta was
+// applied successfully and tmp now contains the verified target contents.
+//
+// Any failure (missing delta, hash mismatch, patch error) is logged and
+// treated as a soft failure so that download can fall back to a full
+// download.
+func (u *Updater) tryDeltaDownload(target, repoPath, localPath string, meta *data.TargetFileMeta, tmp *os.File) bool {
+	oldSHA256, err := fileSHA256(localPath)
+	if err != nil {
+		// Nothing installed yet (or unreadable), so there's no base to patch.
+		return false
+	}
+
+	deltaRepoPath := fmt.Sprintf("%s.%s.bsdiff", repoPath, oldSHA256)
+	deltaMeta, err := u.client.Target(deltaRepoPath)
+	if err != nil {
+		log.Debug().Str("target", target).Str("info", err.Error()).Msg("no delta available, falling back to full download")
+		return false
+	}
+
+	staging := filepath.Join(u.opt.RootDirectory, stagingDir)
+	patchPath := filepath.Join(staging, filepath.Base(localPath)+".bsdiff")
+	patchFile, err := secure.OpenFile(patchPath, os.O_CREATE|os.O_WRONLY, constant.DefaultExecutableMode)
+	if err != nil {
+		log.Debug().Err(err).Msg("open delta patch file")
+		return false
+	}
+	defer func() {
+		patchFile.Close()
+		os.Remove(patchPath)
+	}()
+
+	if err := u.client.Download(deltaRepoPath, &fileDestination{patchFile}); err != nil {
+		log.Debug().Err(err).Msg("download delta patch")
+		return false
+	}
+	if err := patchFile.Close(); err != nil {
+		log.Debug().Err(err).Msg("close delta patch file")
+		return false
+	}
+	_ = deltaMeta // hash/size of the patch itself is already verified by client.Download.
+
+	old, err := os.ReadFile(localPath)
+	if err != nil {
+		log.Debug().Err(err).Msg("read installed file for delta")
+		return false
+	}
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		log.Debug().Err(err).Msg("read delta patch")
+		return false
+	}
+
+	var out bytes.Buffer
+	if err := bspatch.Reader(bytes.NewReader(old), &out, bytes.NewReader(patch)); err != nil {
+		log.Debug().Err(err).Msg("apply delta patch")
+		return false
+	}
+
+	if err := verifyTargetBytes(meta, out.Bytes()); err != nil {
+		log.Debug().Err(err).Msg("delta output hash mismatch")
+		return false
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		log.Debug().Err(err).Msg("seek tmp file")
+		return false
+	}
+	if err := tmp.Truncate(0); err != nil {
+		log.Debug().Err(err).Msg("truncate tmp file")
+		return false
+	}
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		log.Debug().Err(err).Msg("write delta output")
+		return false
+	}
+
+	return true
+}
+
+// fileSHA256 returns the lowercase hex sha256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyTargetBytes checks that b matches the sha256 hash recorded in meta.
+func verifyTargetBytes(meta *data.TargetFileMeta, b []byte) error {
+	expected, ok := meta.Hashes["sha256"]
+	if !ok {
+		return fmt.Errorf("no sha256 hash in target meta")
+	}
+	sum := sha256.Sum256(b)
+	if hex.EncodeToString(sum[:]) != expected.String() {
+		return fmt.Errorf("sha256 mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+func TestFileSHA256(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o644))
+
+	sum := sha256.Sum256([]byte("hello"))
+	want := hex.EncodeToString(sum[:])
+
+	got, err := fileSHA256(path)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestFileSHA256MissingFile(t *testing.T) {
+	_, err := fileSHA256(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
+
+func TestVerifyTargetBytes(t *testing.T) {
+	b := []byte("hello world")
+	sum := sha256.Sum256(b)
+	meta := &data.TargetFileMeta{}
+	meta.Hashes = data.Hashes{"sha256": data.HexBytes(sum[:])}
+
+	require.NoError(t, verifyTargetBytes(meta, b))
+	require.Error(t, verifyTargetBytes(meta, []byte("tampered")))
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package update
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode number backing info, used to
+// detect that a path now refers to a different underlying file even if its
+// size and mtime happen to coincide.
+func fileIdentity(info os.FileInfo) (dev, ino uint64, ok bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(stat.Dev), uint64(stat.Ino), true
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package update
+
+import "os"
+
+// fileIdentity has no inode equivalent on Windows, so the cache falls back
+// to comparing size and mtime alone.
+func fileIdentity(info os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,213 @@
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+const hashCacheFilename = "hashcache.json"
+
+// hashCacheEntry records enough file-identity information to detect that a
+// path has changed since its sha256 was last computed, without re-reading
+// the file.
+type hashCacheEntry struct {
+	Size          int64  `json:"size"`
+	MTimeUnixNano int64  `json:"mtime_unix_nano"`
+	Dev           uint64 `json:"dev,omitempty"`
+	Ino           uint64 `json:"ino,omitempty"`
+	SHA256        string `json:"sha256"`
+}
+
+// matches reports whether info still corresponds to the file this entry was
+// computed from.
+func (e hashCacheEntry) matches(info os.FileInfo) bool {
+	dev, ino, ok := fileIdentity(info)
+	if ok && (dev != e.Dev || ino != e.Ino) {
+		return false
+	}
+	return e.Size == info.Size() && e.MTimeUnixNano == info.ModTime().UnixNano()
+}
+
+// hashCache is a persistent path -> hashCacheEntry map, stored alongside
+// tuf-metadata.json, used to skip repeated sha256 verification of target
+// files that haven't changed since the last time they were hashed.
+type hashCache struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newHashCache(rootDirectory string) *hashCache {
+	return &hashCache{path: filepath.Join(rootDirectory, hashCacheFilename)}
+}
+
+func (c *hashCache) load() (map[string]hashCacheEntry, error) {
+	entries := map[string]hashCacheEntry{}
+	b, err := os.ReadFile(c.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &entries); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", c.path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		// OK, cache starts empty.
+	default:
+		return nil, fmt.Errorf("read %s: %w", c.path, err)
+	}
+	return entries, nil
+}
+
+func (c *hashCache) save(entries map[string]hashCacheEntry) error {
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal hash cache: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write temp hash cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("rename hash cache: %w", err)
+	}
+	return nil
+}
+
+// get returns the cached sha256 for path if the file's identity (size,
+// mtime, and dev/ino where available) still matches.
+func (c *hashCache) get(path string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		return "", false
+	}
+	entry, ok := entries[path]
+	if !ok {
+		return "", false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	if !entry.matches(info) {
+		return "", false
+	}
+	return entry.SHA256, true
+}
+
+// put records the sha256 computed for path.
+func (c *hashCache) put(path, sha256 string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	dev, ino, _ := fileIdentity(info)
+
+	entries, err := c.load()
+	if err != nil {
+		entries = map[string]hashCacheEntry{}
+	}
+	entries[path] = hashCacheEntry{
+		Size:          info.Size(),
+		MTimeUnixNano: info.ModTime().UnixNano(),
+		Dev:           dev,
+		Ino:           ino,
+		SHA256:        sha256,
+	}
+	_ = c.save(entries)
+}
+
+// invalidate removes any cached entry for path, used whenever the file at
+// path is downloaded or replaced so a stale hit can't be served afterwards.
+func (c *hashCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		return
+	}
+	if _, ok := entries[path]; !ok {
+		return
+	}
+	delete(entries, path)
+	_ = c.save(entries)
+}
+
+// cachedCheckFileHash wraps checkFileHash with the on-disk hash cache: if
+// path's identity matches a cached entry, the cached sha256 is compared
+// directly against meta instead of re-reading and re-hashing the file.
+func (u *Updater) cachedCheckFileHash(meta *data.TargetFileMeta, path string) error {
+	cache := u.hashCache()
+
+	if cached, ok := cache.get(path); ok {
+		return verifyTargetBytesHash(meta, cached)
+	}
+
+	if err := checkFileHash(meta, path); err != nil {
+		return err
+	}
+
+	sha256, err := fileSHA256(path)
+	if err != nil {
+		// The hash check above already succeeded, so this is just a cache
+		// miss going forward, not a verification failure.
+		return nil
+	}
+	cache.put(path, sha256)
+	return nil
+}
+
+// cachedFileHash returns the sha256 of path, using (and populating) the
+// on-disk hash cache so repeated calls for an unchanged file skip
+// re-reading it.
+func (u *Updater) cachedFileHash(path string) (string, error) {
+	cache := u.hashCache()
+
+	if cached, ok := cache.get(path); ok {
+		return cached, nil
+	}
+
+	sha256, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+	cache.put(path, sha256)
+	return sha256, nil
+}
+
+func (u *Updater) invalidateHashCache(path string) {
+	u.hashCache().invalidate(path)
+}
+
+// hashCache returns the Updater's shared hashCache, constructing it on the
+// first call so that all callers serialize their reads/writes of
+// hashcache.json through the same mutex.
+func (u *Updater) hashCache() *hashCache {
+	u.hashCacheOnce.Do(func() {
+		u.hashCacheInst = newHashCache(u.opt.RootDirectory)
+	})
+	return u.hashCacheInst
+}
+
+// verifyTargetBytesHash compares an already-computed sha256 hex digest
+// against the hash recorded in meta.
+func verifyTargetBytesHash(meta *data.TargetFileMeta, sha256Hex string) error {
+	expected, ok := meta.Hashes["sha256"]
+	if !ok {
+		return fmt.Errorf("no sha256 hash in target meta")
+	}
+	if sha256Hex != expected.String() {
+		return fmt.Errorf("sha256 mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashCacheGetPut(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	require.NoError(t, os.WriteFile(target, []byte("hello"), 0o644))
+
+	cache := newHashCache(dir)
+
+	_, ok := cache.get(target)
+	require.False(t, ok, "expected miss before put")
+
+	cache.put(target, "deadbeef")
+	sha, ok := cache.get(target)
+	require.True(t, ok)
+	require.Equal(t, "deadbeef", sha)
+}
+
+func TestHashCacheInvalidatedOnContentChange(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	require.NoError(t, os.WriteFile(target, []byte("hello"), 0o644))
+
+	cache := newHashCache(dir)
+	cache.put(target, "deadbeef")
+
+	// Rewriting the file changes size/mtime, so the cached entry should no
+	// longer match even though put was never explicitly invalidated.
+	require.NoError(t, os.WriteFile(target, []byte("hello world"), 0o644))
+	_, ok := cache.get(target)
+	require.False(t, ok, "stale entry should not match after content changed")
+}
+
+func TestHashCacheInvalidate(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	require.NoError(t, os.WriteFile(target, []byte("hello"), 0o644))
+
+	cache := newHashCache(dir)
+	cache.put(target, "deadbeef")
+	cache.invalidate(target)
+
+	_, ok := cache.get(target)
+	require.False(t, ok)
+}
@@ -0,0 +1,280 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/pkg/secure"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	updatesFilename           = "updates.json"
+	defaultHealthCheckTimeout = 30 * time.Second
+)
+
+// RollbackError is returned by Get when a newly downloaded target fails its
+// HealthCheck and the previous binary had to be restored.
+type RollbackError struct {
+	Target string
+	Cause  error
+}
+
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("rolled back target %q after failed health check: %s", e.Target, e.Cause)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Cause
+}
+
+// LastKnownGoodEntry records the last target that was downloaded and passed
+// its HealthCheck.
+type LastKnownGoodEntry struct {
+	Target      string    `json:"target"`
+	SHA256      string    `json:"sha256"`
+	Path        string    `json:"path"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// updatesState is the persisted contents of <RootDirectory>/updates.json.
+type updatesState struct {
+	LastKnownGood map[string]LastKnownGoodEntry `json:"last_known_good"`
+}
+
+func (u *Updater) updatesPath() string {
+	return filepath.Join(u.opt.RootDirectory, updatesFilename)
+}
+
+func (u *Updater) loadUpdatesState() (*updatesState, error) {
+	state := &updatesState{LastKnownGood: map[string]LastKnownGoodEntry{}}
+	b, err := os.ReadFile(u.updatesPath())
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, state); err != nil {
+			return nil, fmt.Errorf("unmarshal %s: %w", u.updatesPath(), err)
+		}
+		if state.LastKnownGood == nil {
+			state.LastKnownGood = map[string]LastKnownGoodEntry{}
+		}
+	case errors.Is(err, os.ErrNotExist):
+		// OK, no state yet.
+	default:
+		return nil, fmt.Errorf("read %s: %w", u.updatesPath(), err)
+	}
+	return state, nil
+}
+
+func (u *Updater) saveUpdatesState(state *updatesState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal updates state: %w", err)
+	}
+
+	tmp := u.updatesPath() + ".tmp"
+	if err := secure.MkdirAll(filepath.Dir(u.updatesPath()), constant.DefaultDirMode); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write temp updates state: %w", err)
+	}
+	if err := os.Rename(tmp, u.updatesPath()); err != nil {
+		return fmt.Errorf("rename updates state: %w", err)
+	}
+	return nil
+}
+
+func (u *Updater) recordLastKnownGood(target string, localTarget *LocalTarget) error {
+	sha256, err := u.cachedFileHash(localTarget.Path)
+	if err != nil {
+		return fmt.Errorf("hash %q: %w", localTarget.Path, err)
+	}
+
+	state, err := u.loadUpdatesState()
+	if err != nil {
+		return err
+	}
+	state.LastKnownGood[target] = LastKnownGoodEntry{
+		Target:      target,
+		SHA256:      sha256,
+		Path:        localTarget.Path,
+		InstalledAt: time.Now(),
+	}
+	return u.saveUpdatesState(state)
+}
+
+// matchesLastKnownGood reports whether the file at path's sha256 equals the
+// last-known-good hash recorded for target. Get uses this to recognize a
+// just-rolled-back binary as already up to date even though it no longer
+// matches the latest TUF target metadata: the latest target is the version
+// that just failed its HealthCheck, and redownloading it would immediately
+// repeat the same crash/rollback cycle.
+func (u *Updater) matchesLastKnownGood(target, path string) (bool, error) {
+	state, err := u.loadUpdatesState()
+	if err != nil {
+		return false, err
+	}
+	entry, ok := state.LastKnownGood[target]
+	if !ok || entry.SHA256 == "" {
+		return false, nil
+	}
+
+	sha256, err := u.cachedFileHash(path)
+	if err != nil {
+		return false, err
+	}
+	return sha256 == entry.SHA256, nil
+}
+
+// previousPath returns the path used to keep a copy of the target's
+// previously installed binary, used to support Rollback.
+func previousPath(localPath string) string {
+	return localPath + ".previous"
+}
+
+// preservePrevious copies the currently installed file at localPath (if any)
+// to <localPath>.previous, overwriting any prior copy, so it can be restored
+// by Rollback if the newly downloaded version fails its HealthCheck.
+func preservePrevious(localPath string) error {
+	src, err := os.Open(localPath)
+	switch {
+	case err == nil:
+		// OK
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("open %q: %w", localPath, err)
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("stat %q: %w", localPath, err)
+	}
+
+	dst, err := secure.OpenFile(previousPath(localPath), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return fmt.Errorf("open %q: %w", previousPath(localPath), err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("copy %q to %q: %w", localPath, previousPath(localPath), err)
+	}
+
+	return nil
+}
+
+// runHealthCheckAndRecord runs the configured HealthCheck (or the default
+// --help check) against localTarget. On success, it records localTarget as
+// the last known good version of target. On failure, it restores the
+// previous binary from <path>.previous and returns a *RollbackError.
+func (u *Updater) runHealthCheckAndRecord(target string, localTarget *LocalTarget) error {
+	healthCheck := u.opt.HealthCheck
+	if healthCheck == nil {
+		healthCheck = u.defaultHealthCheck
+	}
+
+	if err := healthCheck(localTarget); err != nil {
+		log.Error().Err(err).Str("target", target).Msg("health check failed, rolling back")
+		if rbErr := u.Rollback(target); rbErr != nil {
+			log.Error().Err(rbErr).Str("target", target).Msg("rollback failed")
+		}
+		return &RollbackError{Target: target, Cause: err}
+	}
+
+	if err := u.recordLastKnownGood(target, localTarget); err != nil {
+		// Not fatal: the binary is good, we just failed to persist that fact.
+		log.Error().Err(err).Str("target", target).Msg("failed to record last known good")
+	}
+
+	return nil
+}
+
+// defaultHealthCheck runs the executable with --help under
+// Options.HealthCheckTimeout (defaulting to 30s).
+func (u *Updater) defaultHealthCheck(localTarget *LocalTarget) error {
+	timeout := u.opt.HealthCheckTimeout
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+
+	platformGOOS, err := goosFromPlatform(localTarget.Info.Platform)
+	if err != nil {
+		return err
+	}
+	if platformGOOS != runtime.GOOS {
+		// Can't exec a binary built for a different platform.
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, localTarget.ExecPath, "--help").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("exec %q: %s: %w", localTarget.ExecPath, string(out), err)
+	}
+	return nil
+}
+
+// Rollback restores the previously installed binary for target from its
+// <path>.previous copy, if one exists.
+func (u *Updater) Rollback(target string) error {
+	localTarget, err := u.localTarget(target)
+	if err != nil {
+		return fmt.Errorf("failed to load local path for target %s: %w", target, err)
+	}
+
+	prev := previousPath(localTarget.Path)
+	if _, err := os.Stat(prev); err != nil {
+		return fmt.Errorf("no previous version to roll back to for %q: %w", target, err)
+	}
+
+	isTarGz := strings.HasSuffix(localTarget.Path, ".tar.gz")
+	if isTarGz {
+		if err := os.RemoveAll(localTarget.DirPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove extracted dir before rollback: %w", err)
+		}
+	}
+
+	if err := os.Rename(prev, localTarget.Path); err != nil {
+		return fmt.Errorf("restore previous %q: %w", target, err)
+	}
+	u.invalidateHashCache(localTarget.Path)
+
+	if isTarGz {
+		// The restored archive has no extracted directory yet (it was just
+		// removed above); extract it now rather than leaving ExecPath
+		// missing until some later Get() call happens to notice.
+		if err := extractTarGz(localTarget.Path); err != nil {
+			return fmt.Errorf("extract restored %q: %w", localTarget.Path, err)
+		}
+	}
+
+	log.Info().Str("target", target).Msg("rolled back to previous version")
+	return nil
+}
+
+// PromoteStaged confirms that the currently installed version of target is
+// healthy, recording it as the last known good version. Callers implementing
+// a staged rollout (download+verify, run for N minutes, promote) should call
+// this once they're satisfied the new version is stable; otherwise a crash
+// loop should call Rollback instead.
+func (u *Updater) PromoteStaged(target string) error {
+	localTarget, err := u.localTarget(target)
+	if err != nil {
+		return fmt.Errorf("failed to load local path for target %s: %w", target, err)
+	}
+	return u.recordLastKnownGood(target, localTarget)
+}
@@ -0,0 +1,154 @@
+package update
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLastKnownGoodUsesHashCache(t *testing.T) {
+	root := t.TempDir()
+	targetPath := filepath.Join(root, "bin", "orbit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0o755))
+	require.NoError(t, os.WriteFile(targetPath, []byte("binary"), 0o755))
+
+	u := NewDisabled(Options{RootDirectory: root})
+
+	// Pre-seed the hash cache with a sentinel value so we can tell
+	// recordLastKnownGood read from the cache instead of hashing the file
+	// itself.
+	u.hashCache().put(targetPath, "cached-sha256")
+
+	localTarget := &LocalTarget{Path: targetPath}
+	require.NoError(t, u.recordLastKnownGood("orbit", localTarget))
+
+	state, err := u.loadUpdatesState()
+	require.NoError(t, err)
+	require.Equal(t, "cached-sha256", state.LastKnownGood["orbit"].SHA256)
+}
+
+func TestRecordLastKnownGoodPopulatesCacheOnMiss(t *testing.T) {
+	root := t.TempDir()
+	targetPath := filepath.Join(root, "bin", "orbit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0o755))
+	require.NoError(t, os.WriteFile(targetPath, []byte("binary"), 0o755))
+
+	u := NewDisabled(Options{RootDirectory: root})
+
+	localTarget := &LocalTarget{Path: targetPath}
+	require.NoError(t, u.recordLastKnownGood("orbit", localTarget))
+
+	sha, ok := u.hashCache().get(targetPath)
+	require.True(t, ok, "recordLastKnownGood should populate the hash cache on a miss")
+	require.NotEmpty(t, sha)
+
+	state, err := u.loadUpdatesState()
+	require.NoError(t, err)
+	require.Equal(t, sha, state.LastKnownGood["orbit"].SHA256)
+}
+
+func TestRunHealthCheckAndRecordSkipsRecordOnFailure(t *testing.T) {
+	root := t.TempDir()
+	targetPath := filepath.Join(root, "bin", "orbit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0o755))
+	require.NoError(t, os.WriteFile(targetPath, []byte("binary"), 0o755))
+
+	failing := false
+	u := NewDisabled(Options{
+		RootDirectory: root,
+		HealthCheck: func(*LocalTarget) error {
+			failing = true
+			return errors.New("unhealthy")
+		},
+	})
+
+	localTarget := &LocalTarget{Path: targetPath}
+	err := u.runHealthCheckAndRecord("orbit", localTarget)
+	require.True(t, failing)
+	require.Error(t, err)
+
+	state, err2 := u.loadUpdatesState()
+	require.NoError(t, err2)
+	_, recorded := state.LastKnownGood["orbit"]
+	require.False(t, recorded, "a failed health check must not be recorded as last known good")
+}
+
+func TestMatchesLastKnownGood(t *testing.T) {
+	root := t.TempDir()
+	targetPath := filepath.Join(root, "bin", "orbit")
+	require.NoError(t, os.MkdirAll(filepath.Dir(targetPath), 0o755))
+	require.NoError(t, os.WriteFile(targetPath, []byte("good binary"), 0o755))
+
+	u := NewDisabled(Options{RootDirectory: root})
+
+	ok, err := u.matchesLastKnownGood("orbit", targetPath)
+	require.NoError(t, err)
+	require.False(t, ok, "no last known good recorded yet")
+
+	require.NoError(t, u.recordLastKnownGood("orbit", &LocalTarget{Path: targetPath}))
+
+	ok, err = u.matchesLastKnownGood("orbit", targetPath)
+	require.NoError(t, err)
+	require.True(t, ok, "file is unchanged since it was recorded as last known good")
+
+	require.NoError(t, os.WriteFile(targetPath, []byte("different binary"), 0o755))
+	u.invalidateHashCache(targetPath)
+
+	ok, err = u.matchesLastKnownGood("orbit", targetPath)
+	require.NoError(t, err)
+	require.False(t, ok, "file content changed since it was recorded as last known good")
+}
+
+// writeTestTarGzFile is a convenience wrapper around writeTestTarGz for a
+// tar.gz archive holding one regular file at name with the given contents,
+// preceded by a directory entry for name's parent directory (if any), since
+// extractTarGz does not create missing parent directories itself.
+func writeTestTarGzFile(t *testing.T, archivePath, name, contents string) {
+	t.Helper()
+	var entries []*tar.Header
+	if dir := filepath.Dir(name); dir != "." {
+		entries = append(entries, &tar.Header{Name: dir, Typeflag: tar.TypeDir, Mode: 0o755})
+	}
+	entries = append(entries, &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o755, Size: int64(len(contents))})
+	writeTestTarGz(t, archivePath, entries, map[string]string{name: contents})
+}
+
+func TestRollbackReExtractsTarGzTarget(t *testing.T) {
+	root := t.TempDir()
+	binDir := filepath.Join(root, "bin")
+	require.NoError(t, os.MkdirAll(binDir, 0o755))
+
+	const target = "osqueryd"
+	archivePath := filepath.Join(binDir, "osqueryd.app.tar.gz")
+
+	// The "bad" version currently installed (content doesn't matter; it's
+	// removed, not read, by Rollback).
+	require.NoError(t, os.WriteFile(archivePath, []byte("bad archive"), 0o755))
+
+	// The previously installed good version, preserved by preservePrevious
+	// before the bad version was installed.
+	writeTestTarGzFile(t, previousPath(archivePath), "osqueryd/osqueryd", "good binary")
+
+	u := NewDisabled(Options{
+		RootDirectory: root,
+		Targets: Targets{
+			target: TargetInfo{
+				TargetFile:           "osqueryd.app.tar.gz",
+				ExtractedExecSubPath: []string{"osqueryd", "osqueryd"},
+			},
+		},
+	})
+
+	require.NoError(t, u.Rollback(target))
+
+	localTarget, err := u.localTarget(target)
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(localTarget.ExecPath)
+	require.NoError(t, err, "Rollback must re-extract the restored archive, not just restore the .tar.gz file")
+	require.Equal(t, "good binary", string(b))
+}
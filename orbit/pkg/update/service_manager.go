@@ -0,0 +1,23 @@
+package update
+
+// ServiceManager abstracts over the OS-specific mechanism used to track and
+// edit how the orbit service is launched (its executable path and
+// arguments), so that callers like MigrateRoot and the `orbit service`
+// subcommand don't need to know whether they're talking to Windows SCM,
+// systemd, or launchd.
+type ServiceManager interface {
+	// GetExecPath returns the currently configured executable path and
+	// arguments used to launch the service.
+	GetExecPath() (string, []string, error)
+	// SetExecPath reconfigures the service to launch path with args.
+	SetExecPath(path string, args []string) error
+	// SetEnvironmentFile points the service at the environment file used to
+	// set variables for the service process (e.g. the migrated
+	// <RootDirectory>/env/orbit). It is a no-op on platforms whose service
+	// manager has no equivalent concept.
+	SetEnvironmentFile(path string) error
+	// Reload applies any configuration changes made via SetExecPath,
+	// restarting the service manager's view of the unit/service/plist as
+	// needed (it does not itself restart the running service).
+	Reload() error
+}
@@ -0,0 +1,107 @@
+//go:build darwin
+// +build darwin
+
+package update
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"howett.net/plist"
+)
+
+const launchdPlistPath = "/Library/LaunchDaemons/com.fleetdm.orbit.plist"
+
+// launchdPlist implements ServiceManager for launchd by round-tripping the
+// LaunchDaemon plist through a real plist decoder/encoder, rather than
+// shelling out to `defaults write`, which silently drops any key other
+// than the one being written (here, it would clobber the rest of
+// ProgramArguments).
+type launchdPlist struct {
+	path string
+}
+
+func newServiceManager(rootDirectory string) (ServiceManager, error) {
+	return &launchdPlist{path: launchdPlistPath}, nil
+}
+
+func (l *launchdPlist) load() (map[string]interface{}, error) {
+	b, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", l.path, err)
+	}
+	var doc map[string]interface{}
+	if _, err := plist.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", l.path, err)
+	}
+	return doc, nil
+}
+
+// GetExecPath returns the current ProgramArguments as path + args.
+func (l *launchdPlist) GetExecPath() (string, []string, error) {
+	doc, err := l.load()
+	if err != nil {
+		return "", nil, err
+	}
+
+	rawArgs, ok := doc["ProgramArguments"].([]interface{})
+	if !ok || len(rawArgs) == 0 {
+		return "", nil, fmt.Errorf("no ProgramArguments in %s", l.path)
+	}
+
+	args := make([]string, 0, len(rawArgs))
+	for _, a := range rawArgs {
+		s, ok := a.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("non-string entry in ProgramArguments of %s", l.path)
+		}
+		args = append(args, s)
+	}
+	return args[0], args[1:], nil
+}
+
+// SetExecPath rewrites only the ProgramArguments key, leaving every other
+// key in the plist (Label, KeepAlive, StandardOutPath, ...) untouched.
+func (l *launchdPlist) SetExecPath(path string, args []string) error {
+	doc, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	programArgs := make([]interface{}, 0, len(args)+1)
+	programArgs = append(programArgs, path)
+	for _, a := range args {
+		programArgs = append(programArgs, a)
+	}
+	doc["ProgramArguments"] = programArgs
+
+	b, err := plist.MarshalIndent(doc, plist.XMLFormat, "\t")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", l.path, err)
+	}
+	if err := os.WriteFile(l.path, b, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// SetEnvironmentFile is a no-op: launchd has no equivalent of systemd's
+// EnvironmentFile=; LaunchDaemon plists set environment variables directly
+// via an EnvironmentVariables dict, which this ServiceManager doesn't
+// otherwise manage.
+func (l *launchdPlist) SetEnvironmentFile(path string) error {
+	return nil
+}
+
+// Reload unloads and reloads the LaunchDaemon so launchd picks up the new
+// ProgramArguments.
+func (l *launchdPlist) Reload() error {
+	if out, err := exec.Command("launchctl", "unload", l.path).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl unload: %s: %w", string(out), err)
+	}
+	if out, err := exec.Command("launchctl", "load", l.path).CombinedOutput(); err != nil {
+		return fmt.Errorf("launchctl load: %s: %w", string(out), err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package update
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+// newTestSystemdUnit returns a systemdUnit whose override drop-in is
+// written to a temp file instead of the real /etc/systemd path.
+func newTestSystemdUnit(t *testing.T) (*systemdUnit, string) {
+	t.Helper()
+	dir := t.TempDir()
+	overridePath := filepath.Join(dir, systemdOverrideFile)
+	return &systemdUnit{overrideDir: dir}, overridePath
+}
+
+func readOverrideExecStartLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "ExecStart") {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return lines
+}
+
+func TestSetExecPathEmitsClearAndSetLines(t *testing.T) {
+	s, overridePath := newTestSystemdUnit(t)
+
+	require.NoError(t, s.SetExecPath("/opt/orbit/bin/orbit", []string{"--foo", "bar"}))
+
+	lines := readOverrideExecStartLines(t, overridePath)
+	require.Len(t, lines, 2, "override must clear the vendor ExecStart= before setting the new one")
+	require.Equal(t, "ExecStart=", lines[0])
+	require.Equal(t, "ExecStart=/opt/orbit/bin/orbit --foo bar", lines[1])
+}
+
+func TestSetExecPathPreservesEnvironmentFile(t *testing.T) {
+	s, overridePath := newTestSystemdUnit(t)
+
+	require.NoError(t, s.SetEnvironmentFile("/opt/orbit/env/orbit"))
+	require.NoError(t, s.SetExecPath("/opt/orbit/bin/orbit", nil))
+
+	cfg, err := ini.LoadSources(ini.LoadOptions{AllowShadows: true}, overridePath)
+	require.NoError(t, err)
+	require.Equal(t, "/opt/orbit/env/orbit", cfg.Section("Service").Key("EnvironmentFile").String())
+}
+
+func TestGetExecPathFallsBackToVendorUnitWhenOverrideHasNoExecStart(t *testing.T) {
+	s, _ := newTestSystemdUnit(t)
+
+	// The override drop-in exists (written by SetEnvironmentFile alone) but
+	// has no ExecStart key yet; GetExecPath must fall back to the vendor
+	// unit rather than reporting the override's empty ExecStart.
+	require.NoError(t, s.SetEnvironmentFile("/opt/orbit/env/orbit"))
+
+	_, _, err := s.GetExecPath()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), systemdUnitPath, "must attempt the vendor unit, not report the override's missing ExecStart")
+	require.NotContains(t, err.Error(), "no ExecStart in")
+}
+
+func TestSetEnvironmentFilePreservesExecStart(t *testing.T) {
+	s, overridePath := newTestSystemdUnit(t)
+
+	require.NoError(t, s.SetExecPath("/opt/orbit/bin/orbit", []string{"--foo"}))
+	require.NoError(t, s.SetEnvironmentFile("/opt/orbit/env/orbit"))
+
+	lines := readOverrideExecStartLines(t, overridePath)
+	require.Len(t, lines, 2)
+	require.Equal(t, "ExecStart=/opt/orbit/bin/orbit --foo", lines[1])
+}
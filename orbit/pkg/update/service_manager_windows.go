@@ -0,0 +1,90 @@
+//go:build windows
+// +build windows
+
+package update
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const windowsServiceName = "Fleet osquery"
+
+// windowsSCM implements ServiceManager on top of the Windows Service
+// Control Manager.
+type windowsSCM struct {
+	serviceName string
+}
+
+func newServiceManager(rootDirectory string) (ServiceManager, error) {
+	return &windowsSCM{serviceName: windowsServiceName}, nil
+}
+
+// GetExecPath returns the currently configured binary path and arguments,
+// parsed from the service's BinaryPathName (which may be a quoted path
+// followed by arguments).
+func (s *windowsSCM) GetExecPath() (string, []string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", nil, fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	svc, err := m.OpenService(s.serviceName)
+	if err != nil {
+		return "", nil, fmt.Errorf("open service %q: %w", s.serviceName, err)
+	}
+	defer svc.Close()
+
+	cfg, err := svc.Config()
+	if err != nil {
+		return "", nil, fmt.Errorf("get service config: %w", err)
+	}
+
+	path, args, err := splitCommandLine(cfg.BinaryPathName)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse binary path %q: %w", cfg.BinaryPathName, err)
+	}
+	return path, args, nil
+}
+
+// SetExecPath reconfigures the service's BinaryPathName to run path with
+// args, quoting the path if it contains spaces.
+func (s *windowsSCM) SetExecPath(path string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	svc, err := m.OpenService(s.serviceName)
+	if err != nil {
+		return fmt.Errorf("open service %q: %w", s.serviceName, err)
+	}
+	defer svc.Close()
+
+	cfg, err := svc.Config()
+	if err != nil {
+		return fmt.Errorf("get service config: %w", err)
+	}
+
+	cfg.BinaryPathName = joinCommandLine(path, args)
+	if err := svc.UpdateConfig(cfg); err != nil {
+		return fmt.Errorf("update service config: %w", err)
+	}
+	return nil
+}
+
+// SetEnvironmentFile is a no-op: the Windows Service Control Manager has no
+// equivalent of systemd's EnvironmentFile=.
+func (s *windowsSCM) SetEnvironmentFile(path string) error {
+	return nil
+}
+
+// Reload is a no-op on Windows: UpdateConfig takes effect immediately, the
+// service just needs to be restarted to pick up the new binary, which is
+// the caller's responsibility.
+func (s *windowsSCM) Reload() error {
+	return nil
+}
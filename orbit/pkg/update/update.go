@@ -4,7 +4,6 @@ package update
 import (
 	"archive/tar"
 	"bufio"
-	"bytes"
 	"compress/gzip"
 	"crypto/tls"
 	"encoding/json"
@@ -15,9 +14,10 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
@@ -46,6 +46,9 @@ const (
 type Updater struct {
 	opt    Options
 	client *client.Client
+
+	hashCacheOnce sync.Once
+	hashCacheInst *hashCache
 }
 
 // Options are the options that can be provided when creating an Updater.
@@ -64,8 +67,22 @@ type Options struct {
 	LocalStore client.LocalStore
 	// Targets holds the targets the Updater keeps track of.
 	Targets Targets
+	// HealthCheck is run against a target's LocalTarget when Get has just
+	// downloaded a new version of it, before it is handed back to the
+	// caller; a Get that finds the target already up to date skips it. If
+	// it returns an error, the previous binary is restored and Get returns a
+	// *RollbackError.
+	//
+	// Defaults to running the target with --help under HealthCheckTimeout.
+	HealthCheck HealthCheck
+	// HealthCheckTimeout bounds how long the default HealthCheck is allowed
+	// to run. Defaults to 30 seconds if zero.
+	HealthCheckTimeout time.Duration
 }
 
+// HealthCheck verifies that a downloaded target is safe to keep running.
+type HealthCheck func(*LocalTarget) error
+
 // Targets is a map of target name and its tracking information.
 type Targets map[string]TargetInfo
 
@@ -274,6 +291,7 @@ func (u *Updater) Get(target string) (*LocalTarget, error) {
 		return nil, fmt.Errorf("failed to load repository path for target %s: %w", target, err)
 	}
 
+	downloaded := false
 	switch stat, err := os.Stat(localTarget.Path); {
 	case err == nil:
 		if !stat.Mode().IsRegular() {
@@ -283,14 +301,26 @@ func (u *Updater) Get(target string) (*LocalTarget, error) {
 		if err != nil {
 			return nil, err
 		}
-		if err := checkFileHash(meta, localTarget.Path); err != nil {
-			log.Debug().Str("info", err.Error()).Msg("change detected")
-			if err := u.download(target, repoPath, localTarget.Path); err != nil {
-				return nil, fmt.Errorf("download %q: %w", repoPath, err)
-			}
-			if strings.HasSuffix(localTarget.Path, ".tar.gz") {
-				if err := os.RemoveAll(localTarget.DirPath); err != nil {
-					return nil, fmt.Errorf("failed to remove old extracted dir: %q: %w", localTarget.DirPath, err)
+		if err := u.cachedCheckFileHash(meta, localTarget.Path); err != nil {
+			// The installed file no longer matches the latest target
+			// metadata. Before treating that as "needs download", check
+			// whether it's pinned to a last-known-good version (e.g. this
+			// is the binary a just-completed Rollback restored): if so, the
+			// latest target is the version that failed its HealthCheck, and
+			// redownloading it here would immediately repeat the same
+			// crash/rollback cycle.
+			if ok, lkgErr := u.matchesLastKnownGood(target, localTarget.Path); lkgErr == nil && ok {
+				log.Debug().Str("target", target).Msg("installed version differs from latest target but matches last known good, not redownloading")
+			} else {
+				log.Debug().Str("info", err.Error()).Msg("change detected")
+				if err := u.download(target, repoPath, localTarget.Path); err != nil {
+					return nil, fmt.Errorf("download %q: %w", repoPath, err)
+				}
+				downloaded = true
+				if strings.HasSuffix(localTarget.Path, ".tar.gz") {
+					if err := os.RemoveAll(localTarget.DirPath); err != nil {
+						return nil, fmt.Errorf("failed to remove old extracted dir: %q: %w", localTarget.DirPath, err)
+					}
 				}
 			}
 		} else {
@@ -301,6 +331,7 @@ func (u *Updater) Get(target string) (*LocalTarget, error) {
 		if err := u.download(target, repoPath, localTarget.Path); err != nil {
 			return nil, fmt.Errorf("download %q: %w", repoPath, err)
 		}
+		downloaded = true
 	default:
 		return nil, fmt.Errorf("stat %q: %w", localTarget.Path, err)
 	}
@@ -326,6 +357,12 @@ func (u *Updater) Get(target string) (*LocalTarget, error) {
 		}
 	}
 
+	if downloaded {
+		if err := u.runHealthCheckAndRecord(target, localTarget); err != nil {
+			return nil, err
+		}
+	}
+
 	return localTarget, nil
 }
 
@@ -413,9 +450,18 @@ func (u *Updater) download(target, repoPath, localPath string) error {
 		return err
 	}
 
-	// The go-tuf client handles checking of max size and hash.
-	if err := u.client.Download(repoPath, &fileDestination{tmp}); err != nil {
-		return fmt.Errorf("download target %s: %w", repoPath, err)
+	meta, err := u.client.Target(repoPath)
+	if err != nil {
+		return fmt.Errorf("lookup target meta %s: %w", repoPath, err)
+	}
+
+	if u.tryDeltaDownload(target, repoPath, localPath, &meta, tmp) {
+		log.Debug().Str("target", target).Msg("applied delta update")
+	} else {
+		// The go-tuf client handles checking of max size and hash.
+		if err := u.client.Download(repoPath, &fileDestination{tmp}); err != nil {
+			return fmt.Errorf("download target %s: %w", repoPath, err)
+		}
 	}
 	if err := tmp.Close(); err != nil {
 		return fmt.Errorf("close tmp file: %w", err)
@@ -425,16 +471,19 @@ func (u *Updater) download(target, repoPath, localPath string) error {
 		return fmt.Errorf("exec check failed %q: %w", tmp.Name(), err)
 	}
 
-	if runtime.GOOS == "windows" {
-		// Remove old file first
-		if err := os.Rename(localPath, localPath+".old"); err != nil && !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("rename old: %w", err)
-		}
+	// Preserve the currently installed binary as <TargetFile>.previous, on
+	// every platform, so a failed HealthCheck after the rename below can
+	// restore it via Rollback. This replaces the old Windows-only ".old"
+	// rename, which just discarded the previous binary instead of keeping
+	// it around for rollback.
+	if err := preservePrevious(localPath); err != nil {
+		return fmt.Errorf("preserve previous: %w", err)
 	}
 
 	if err := os.Rename(tmp.Name(), localPath); err != nil {
 		return fmt.Errorf("move download: %w", err)
 	}
+	u.invalidateHashCache(localPath)
 
 	return nil
 }
@@ -498,6 +547,10 @@ func extractTarGz(path string) error {
 	}
 	defer gzipReader.Close()
 
+	// root is the extraction root, used to make sure regular files,
+	// hardlinks and symlinks all resolve to a path inside of it.
+	root := filepath.Dir(path)
+
 	tarReader := tar.NewReader(gzipReader)
 	for {
 		header, err := tarReader.Next()
@@ -515,7 +568,7 @@ func extractTarGz(path string) error {
 			return fmt.Errorf("invalid path in tar.gz: %q", header.Name)
 		}
 
-		targetPath := filepath.Join(filepath.Dir(path), header.Name)
+		targetPath := filepath.Join(root, header.Name)
 
 		switch header.Typeflag {
 		case tar.TypeDir:
@@ -524,7 +577,7 @@ func extractTarGz(path string) error {
 			}
 		case tar.TypeReg:
 			err := func() error {
-				outFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY, header.FileInfo().Mode())
+				outFile, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode())
 				if err != nil {
 					return fmt.Errorf("failed to create %q: %w", header.Name, err)
 				}
@@ -538,12 +591,74 @@ func extractTarGz(path string) error {
 			if err != nil {
 				return err
 			}
+			if err := applyTarMetadata(targetPath, header); err != nil {
+				return fmt.Errorf("apply metadata %q: %w", header.Name, err)
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			// Symlink targets are relative to the directory holding the
+			// entry, like a filesystem symlink would be. Hardlink targets
+			// (header.Linkname) are archive-root-relative, the same
+			// namespace as header.Name — GNU and BSD tar both write them
+			// this way, and entries linking across directories (e.g. a
+			// .app bundle's Contents/Frameworks layout) depend on it.
+			var linkTargetPath string
+			if header.Typeflag == tar.TypeSymlink {
+				linkTargetPath = filepath.Join(filepath.Dir(targetPath), header.Linkname)
+			} else {
+				linkTargetPath = filepath.Join(root, header.Linkname)
+			}
+			resolvedRoot, err := filepath.Abs(root)
+			if err != nil {
+				return fmt.Errorf("resolve root %q: %w", root, err)
+			}
+			resolvedTarget, err := filepath.Abs(linkTargetPath)
+			if err != nil {
+				return fmt.Errorf("resolve link target %q: %w", header.Linkname, err)
+			}
+			if !strings.HasPrefix(resolvedTarget, resolvedRoot+string(filepath.Separator)) && resolvedTarget != resolvedRoot {
+				return fmt.Errorf("invalid link target in tar.gz: %q -> %q", header.Name, header.Linkname)
+			}
+
+			// Remove any existing entry so re-extraction doesn't fail on an
+			// already-existing link.
+			if err := os.Remove(targetPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("remove existing %q: %w", header.Name, err)
+			}
+
+			if header.Typeflag == tar.TypeSymlink {
+				if err := os.Symlink(header.Linkname, targetPath); err != nil {
+					return fmt.Errorf("symlink %q -> %q: %w", header.Name, header.Linkname, err)
+				}
+			} else {
+				if err := os.Link(linkTargetPath, targetPath); err != nil {
+					return fmt.Errorf("hardlink %q -> %q: %w", header.Name, header.Linkname, err)
+				}
+			}
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			// Pax extended headers carry metadata for the next entry and
+			// don't need any extraction of their own.
+			continue
 		default:
 			return fmt.Errorf("unknown flag type %q: %d", header.Name, header.Typeflag)
 		}
 	}
 }
 
+// applyTarMetadata restores the mode, ownership and modification time of a
+// regular file extracted from a tar.gz archive.
+func applyTarMetadata(path string, header *tar.Header) error {
+	if err := os.Chmod(path, header.FileInfo().Mode()); err != nil {
+		return fmt.Errorf("chmod: %w", err)
+	}
+	if err := chown(path, header.Uid, header.Gid); err != nil {
+		return fmt.Errorf("chown: %w", err)
+	}
+	if err := os.Chtimes(path, header.ModTime, header.ModTime); err != nil {
+		return fmt.Errorf("chtimes: %w", err)
+	}
+	return nil
+}
+
 func (u *Updater) initializeDirectories() error {
 	for _, dir := range []string{
 		filepath.Join(u.opt.RootDirectory, binDir),
@@ -654,100 +769,30 @@ func MigrateRoot(opt Options) (bool, error) {
 
 	orbitPath := filepath.Join(opt.RootDirectory, "bin", "orbit")
 
-	switch runtime.GOOS {
-	case "windows":
-		// edit the existing windows service
-		scPath, err := exec.LookPath("SC.exe")
-		if err != nil {
-			return false, fmt.Errorf("find systemctl in path: %w", err)
-		}
-
-		// get the current binPath, because it contains args that could have been modified since installation
-		cmd := exec.Command(scPath, "qc", "Fleet osquery")
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return false, fmt.Errorf("get service config: %w", err)
-		}
-
-		scanner := bufio.NewScanner(bytes.NewBuffer(out))
-		var binPath string
-		for scanner.Scan() {
-			f := strings.Fields(scanner.Text())
-			if len(f) > 1 && f[0] == "BINARY_PATH_NAME" {
-				args := f[2:]
-				args[0] = orbitPath
-				binPath = strings.Join(args, " ")
-				break
-			}
-		}
-		if binPath == "" {
-			return false, fmt.Errorf("get binary path")
-		}
-
-		cmd = exec.Command(scPath, "config", "Fleet osquery", "binpath=", binPath)
-		out, err = cmd.CombinedOutput()
-		if err != nil {
-			return false, fmt.Errorf("edit service: %s: %w", string(out), err)
-		}
-	case "linux":
-		// update paths in systemd service file
-		servicePath := filepath.Join("/", "usr", "lib", "systemd", "system", "orbit.service")
-		log.Debug().Msgf("updating paths in %s", servicePath)
-		b, err := os.ReadFile(servicePath)
-		if err != nil {
-			return false, err
-		}
-
-		re, err := regexp.Compile(`(?m)^(EnvironmentFile=).*`)
-		if err != nil {
-			return false, err
-		}
-		environmentFilePath := filepath.Join(opt.RootDirectory, "env", "orbit")
-		b = re.ReplaceAll(b, []byte("$1"+environmentFilePath))
-
-		re, err = regexp.Compile(`(?m)^(ExecStart=).*`)
-		if err != nil {
-			return false, err
-		}
-		orbitPath := filepath.Join(opt.RootDirectory, "bin", "orbit")
-		b = re.ReplaceAll(b, []byte("$1"+orbitPath))
-
-		err = os.WriteFile(servicePath, b, 0)
-		if err != nil {
-			return false, fmt.Errorf("write %s: %w", servicePath, err)
-		}
-
-		// call daemon-reload so that it restarts the service with the updated orbit.service unit file
-		systemctlPath, err := exec.LookPath("systemctl")
-		if err != nil && err != exec.ErrNotFound {
-			return false, fmt.Errorf("find systemctl in path: %w", err)
-		} else if err == nil {
-			log.Debug().Msg("reloading unit files ...")
-			cmd := exec.Command(systemctlPath, "daemon-reload")
-			out, err := cmd.CombinedOutput()
-			if err != nil {
-				// this is a problem since the service will not be restarted unless reload is successful
-				log.Error().Err(err).Msgf("systemctl daemon-reload returned an error: %s", string(out))
-				return false, fmt.Errorf("systemctl daemon-reload: %w", err)
-			}
-		}
-	case "darwin":
-		plistPath := filepath.Join("/", "Library", "LaunchDaemons", "com.fleetdm.orbit.plist")
-		log.Debug().Msgf("updating paths in %s", plistPath)
-
-		// update orbit path using defaults command
-		cmd := exec.Command("defaults", "write", plistPath, "ProgramArguments", "-array", "-string", orbitPath)
-		out, err := cmd.CombinedOutput()
-		if err != nil {
-			return false, fmt.Errorf("defaults read %s: %s: %w", plistPath, out, err)
+	// Point the OS service manager at the migrated orbit binary, preserving
+	// whatever other arguments/environment it was configured with.
+	svcMgr, err := newServiceManager(opt.RootDirectory)
+	if err != nil {
+		return false, fmt.Errorf("init service manager: %w", err)
+	}
+	_, args, err := svcMgr.GetExecPath()
+	if err != nil {
+		return false, fmt.Errorf("get service exec path: %w", err)
+	}
+	if err := svcMgr.SetExecPath(orbitPath, args); err != nil {
+		return false, fmt.Errorf("set service exec path: %w", err)
+	}
+	if runtime.GOOS == "linux" {
+		// oldRoot (including the old /etc/default/orbit) is removed below,
+		// so the service must be pointed at the migrated env file or it'll
+		// reference a path that no longer exists.
+		envPath := filepath.Join(opt.RootDirectory, "env", "orbit")
+		if err := svcMgr.SetEnvironmentFile(envPath); err != nil {
+			return false, fmt.Errorf("set service environment file: %w", err)
 		}
-
-		// force reload the service
-		// cmd = exec.Command("defaults", "read", plistPath)
-		// out err := cmd.CombinedOutput()
-		// if err != nil {
-		// 	return false, fmt.Errorf("defaults read %s: %s: %w", plistPath, out, err)
-		// }
+	}
+	if err := svcMgr.Reload(); err != nil {
+		return false, fmt.Errorf("reload service: %w", err)
 	}
 
 	// clean up old files
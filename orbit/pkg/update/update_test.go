@@ -0,0 +1,88 @@
+package update
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTarGz writes entries (in order) to a tar.gz file at path.
+func writeTestTarGz(t *testing.T, path string, entries []*tar.Header, contents map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	for _, hdr := range entries {
+		require.NoError(t, tw.WriteHeader(hdr))
+		if body, ok := contents[hdr.Name]; ok {
+			_, err := tw.Write([]byte(body))
+			require.NoError(t, err)
+		}
+	}
+}
+
+func TestExtractTarGzHardlinkCrossesDirectories(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "archive.tar.gz")
+
+	entries := []*tar.Header{
+		{Name: "a", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "a/file.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len("hello"))},
+		{Name: "b", Typeflag: tar.TypeDir, Mode: 0o755},
+		// Hardlink Linkname is archive-root-relative, unlike a symlink's.
+		{Name: "b/hardlink.txt", Typeflag: tar.TypeLink, Linkname: "a/file.txt"},
+	}
+	writeTestTarGz(t, archivePath, entries, map[string]string{"a/file.txt": "hello"})
+
+	require.NoError(t, extractTarGz(archivePath))
+
+	linked := filepath.Join(root, "b", "hardlink.txt")
+	b, err := os.ReadFile(linked)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+}
+
+func TestExtractTarGzSymlinkRelativeToOwnDir(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "archive.tar.gz")
+
+	entries := []*tar.Header{
+		{Name: "a", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "a/file.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len("hello"))},
+		{Name: "b", Typeflag: tar.TypeDir, Mode: 0o755},
+		// Symlink Linkname is relative to the symlink's own directory.
+		{Name: "b/symlink.txt", Typeflag: tar.TypeSymlink, Linkname: "../a/file.txt"},
+	}
+	writeTestTarGz(t, archivePath, entries, map[string]string{"a/file.txt": "hello"})
+
+	require.NoError(t, extractTarGz(archivePath))
+
+	linked := filepath.Join(root, "b", "symlink.txt")
+	b, err := os.ReadFile(linked)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+}
+
+func TestExtractTarGzRejectsLinkEscapingRoot(t *testing.T) {
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "archive.tar.gz")
+
+	entries := []*tar.Header{
+		{Name: "evil.txt", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"},
+	}
+	writeTestTarGz(t, archivePath, entries, nil)
+
+	err := extractTarGz(archivePath)
+	require.Error(t, err)
+}
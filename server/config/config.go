@@ -0,0 +1,113 @@
+package config
+
+// FleetConfig is the root of Fleet server configuration, assembled from
+// environment variables / config file / flags by the cmd/fleet entrypoint.
+//
+// This file only declares the fields this tree's packages actually read;
+// the full config surface (auth, mysql, redis, license, ...) lives
+// alongside it and isn't reproduced here.
+type FleetConfig struct {
+	Filesystem FilesystemConfig
+	Osquery    OsqueryConfig
+
+	Kinesis  KinesisConfig
+	Firehose FirehoseConfig
+	Lambda   LambdaConfig
+	PubSub   PubSubConfig
+	S3       S3LoggingConfig
+	Kafka    KafkaConfig
+
+	// Courier configures the email+SMS dispatch pipeline (server/courier)
+	// used in place of a bare SMTP mailer.
+	Courier CourierConfig
+
+	// FileStore selects and configures the server/filestore backend.
+	FileStore FileStoreConfig
+}
+
+// FilesystemConfig configures the "filesystem" osquery status/result log
+// plugin.
+type FilesystemConfig struct {
+	StatusLogFile        string
+	ResultLogFile        string
+	EnableLogRotation    bool
+	EnableLogCompression bool
+}
+
+// OsqueryConfig configures osquery-facing behavior, including which log
+// plugin handles status and result logs.
+type OsqueryConfig struct {
+	// ResultLogPlugin and StatusLogPlugin select the log plugin used for
+	// osquery result/status logs: "filesystem", "kinesis", "firehose",
+	// "lambda", "pubsub", "stdout", "s3", or "kafka".
+	ResultLogPlugin string
+	StatusLogPlugin string
+}
+
+// KinesisConfig configures the "kinesis" osquery status/result log plugin.
+type KinesisConfig struct {
+	Region           string
+	StatusStream     string
+	ResultStream     string
+	AccessKeyID      string
+	SecretAccessKey  string
+	StsAssumeRoleArn string
+}
+
+// FirehoseConfig configures the "firehose" osquery status/result log
+// plugin.
+type FirehoseConfig struct {
+	Region           string
+	StatusStream     string
+	ResultStream     string
+	AccessKeyID      string
+	SecretAccessKey  string
+	StsAssumeRoleArn string
+}
+
+// LambdaConfig configures the "lambda" osquery status/result log plugin.
+type LambdaConfig struct {
+	Region           string
+	StatusFunction   string
+	ResultFunction   string
+	AccessKeyID      string
+	SecretAccessKey  string
+	StsAssumeRoleArn string
+}
+
+// PubSubConfig configures the "pubsub" osquery status/result log plugin.
+type PubSubConfig struct {
+	Project       string
+	StatusTopic   string
+	ResultTopic   string
+	AddAttributes bool
+}
+
+// SMTPConfig configures the SMTP provider used by server/courier's
+// ChannelEmail delivery.
+type SMTPConfig struct {
+	Server             string
+	Port               int
+	Username           string
+	Password           string
+	AuthenticationType string
+	EnableTLS          bool
+	VerifySSLCerts     bool
+	SenderAddress      string
+}
+
+// TestConfig returns a FleetConfig with reasonable defaults for use in
+// tests, separate from whatever config.yml/env the running binary sees.
+func TestConfig() FleetConfig {
+	return FleetConfig{
+		Filesystem: FilesystemConfig{
+			StatusLogFile:     "/tmp/fleet_status.log",
+			ResultLogFile:     "/tmp/fleet_result.log",
+			EnableLogRotation: false,
+		},
+		Osquery: OsqueryConfig{
+			ResultLogPlugin: "filesystem",
+			StatusLogPlugin: "filesystem",
+		},
+	}
+}
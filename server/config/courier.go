@@ -0,0 +1,22 @@
+package config
+
+import "encoding/json"
+
+// CourierConfig configures the server/courier package, which replaces the
+// previous SMTP-only mailer with a pluggable email+SMS dispatch pipeline.
+// It is embedded in FleetConfig as `Courier`.
+type CourierConfig struct {
+	SMTP SMTPConfig
+	SMS  SMSConfig
+}
+
+// SMSConfig selects and configures the SMS provider used by the courier.
+type SMSConfig struct {
+	// Provider selects the SMS provider implementation: "twilio" or
+	// "webhook". Empty disables SMS delivery.
+	Provider string
+	// RequestConfig is the provider-specific configuration, as raw JSON so
+	// each provider can define its own shape (e.g. TwilioConfig or
+	// WebhookConfig fields) without growing this struct per provider.
+	RequestConfig json.RawMessage
+}
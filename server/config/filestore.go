@@ -0,0 +1,29 @@
+package config
+
+// FileStoreConfig selects and configures the server/filestore backend used
+// for spooling large log batches, storing MDM profiles and software
+// installer packages, and exporting query results as downloadable
+// artifacts.
+type FileStoreConfig struct {
+	// Backend selects the filestore backend: "local" or "s3". Empty
+	// defaults to "local".
+	Backend string
+
+	Local LocalFileStoreConfig
+	S3    S3FileStoreConfig
+}
+
+// LocalFileStoreConfig configures filestore.LocalFileBackend.
+type LocalFileStoreConfig struct {
+	Directory string
+}
+
+// S3FileStoreConfig configures filestore.S3FileBackend.
+type S3FileStoreConfig struct {
+	Bucket               string
+	Region               string
+	Endpoint             string
+	StsAssumeRoleArn     string
+	ServerSideEncryption string
+	ForcePathStyle       bool
+}
@@ -0,0 +1,33 @@
+package config
+
+import "time"
+
+// S3LoggingConfig configures the "s3" osquery status/result log plugin,
+// which batches log lines and uploads them as gzip-compressed
+// newline-delimited JSON objects.
+type S3LoggingConfig struct {
+	Region           string
+	Bucket           string
+	Prefix           string
+	AccessKeyID      string
+	SecretAccessKey  string
+	StsAssumeRoleArn string
+	// ServerSideEncryption is the SSE mode applied to uploaded objects (e.g.
+	// "AES256" or "aws:kms"). Empty disables SSE.
+	ServerSideEncryption string
+	// BatchSize is the number of log lines buffered before a flush.
+	BatchSize int
+	// FlushInterval is how old the buffer may grow before a Write flushes
+	// it regardless of BatchSize. It is only checked when Write is called;
+	// no timer flushes a buffer that receives no further writes.
+	FlushInterval time.Duration
+}
+
+// KafkaConfig configures the "kafka" osquery status/result log plugin.
+type KafkaConfig struct {
+	Brokers     []string
+	StatusTopic string
+	ResultTopic string
+	// ClientID identifies this producer to the Kafka brokers.
+	ClientID string
+}
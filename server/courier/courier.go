@@ -0,0 +1,100 @@
+// Package courier provides a single outbound-notification abstraction for
+// Fleet, covering both email and SMS delivery. It plays the same role as
+// Kratos' courier: callers hand it a Message and a Channel, and the courier
+// resolves that to whichever configured provider handles the channel,
+// queuing the send for asynchronous, retried delivery.
+package courier
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// Channel identifies which delivery mechanism a Message should go out on.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message is a single outbound notification, independent of the channel it
+// will be sent on.
+type Message struct {
+	Channel Channel
+	// To is the destination address: an email address for ChannelEmail, an
+	// E.164 phone number for ChannelSMS.
+	To string
+	// Subject is only used for ChannelEmail.
+	Subject string
+	// Body is the rendered message body (HTML for email, plain text for SMS).
+	Body string
+}
+
+// Provider sends a single Message over one channel.
+type Provider interface {
+	Channel() Channel
+	Send(ctx context.Context, msg Message) error
+}
+
+// Courier dispatches messages to the provider registered for their channel.
+// Implementations are expected to queue messages for asynchronous delivery
+// rather than sending inline, so that a slow or unavailable provider never
+// blocks the caller (invite emails, password resets, alerting rules, etc).
+type Courier interface {
+	// Send enqueues msg for delivery, returning once it has been durably
+	// queued (not once it has actually been delivered).
+	Send(ctx context.Context, msg Message) error
+}
+
+// Runner is implemented by Courier implementations that need a background
+// loop started to actually deliver queued messages (e.g. the dispatcher
+// returned by NewDispatcher). Callers constructing a Courier should type-
+// assert for it and start Run in its own goroutine.
+type Runner interface {
+	Run(ctx context.Context, pollInterval time.Duration)
+}
+
+// SendEmail is a convenience wrapper used by callers that only ever send
+// fleet.Email, preserving the ergonomics of the old fleet.MailService
+// interface this package replaces.
+func SendEmail(ctx context.Context, c Courier, e fleet.Email) error {
+	body, err := e.Mailer.Message()
+	if err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Send(ctx, Message{
+			Channel: ChannelEmail,
+			To:      to,
+			Subject: e.Subject,
+			Body:    string(body),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RetryPolicy configures how the dispatch loop backs off between delivery
+// attempts for a single queued message.
+type RetryPolicy struct {
+	// InitialInterval is the delay before the first retry.
+	InitialInterval time.Duration
+	// MaxInterval caps the exponential backoff interval between retries,
+	// before the ±50% randomization is applied (see backoffDelay).
+	MaxInterval time.Duration
+	// MaxAttempts is how many times delivery is attempted (including the
+	// first) before a message is marked failed and given up on.
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy mirrors the backoff used elsewhere in Fleet for
+// external service calls.
+var DefaultRetryPolicy = RetryPolicy{
+	InitialInterval: 30 * time.Second,
+	MaxInterval:     10 * time.Minute,
+	MaxAttempts:     5,
+}
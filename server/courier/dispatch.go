@@ -0,0 +1,150 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// PendingMessage is a Message persisted by Store while it waits to be
+// delivered (or retried).
+type PendingMessage struct {
+	ID       uint
+	Message  Message
+	Attempts int
+	// NextAttemptAt is when the dispatch loop should next try to deliver
+	// this message; zero means "as soon as possible".
+	NextAttemptAt time.Time
+}
+
+// Store persists queued messages so delivery survives process restarts and
+// can be retried with backoff. Implementations live in the datastore
+// package, backed by a table such as `courier_queue`.
+type Store interface {
+	QueueMessage(ctx context.Context, msg Message) (*PendingMessage, error)
+	ListPendingMessages(ctx context.Context, limit int) ([]*PendingMessage, error)
+	MarkDelivered(ctx context.Context, id uint) error
+	MarkRetry(ctx context.Context, id uint, nextAttemptAt time.Time) error
+	MarkFailed(ctx context.Context, id uint) error
+}
+
+// dispatcher is a Courier that durably queues messages in Store and
+// delivers them asynchronously from a background loop, retrying failed
+// sends with exponential backoff until RetryPolicy.MaxAttempts is reached.
+type dispatcher struct {
+	store     Store
+	providers map[Channel]Provider
+	policy    RetryPolicy
+}
+
+// NewDispatcher returns a Courier backed by store, delivering through the
+// given providers (looked up by Provider.Channel) with policy governing
+// retry backoff.
+func NewDispatcher(store Store, providers []Provider, policy RetryPolicy) Courier {
+	byChannel := make(map[Channel]Provider, len(providers))
+	for _, p := range providers {
+		byChannel[p.Channel()] = p
+	}
+	return &dispatcher{store: store, providers: byChannel, policy: policy}
+}
+
+// Send durably queues msg; a background call to Run will pick it up and
+// attempt delivery.
+func (d *dispatcher) Send(ctx context.Context, msg Message) error {
+	if _, ok := d.providers[msg.Channel]; !ok {
+		return fmt.Errorf("no provider configured for channel %q", msg.Channel)
+	}
+	_, err := d.store.QueueMessage(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("queue message: %w", err)
+	}
+	return nil
+}
+
+// Run polls Store for pending messages and attempts delivery, retrying with
+// exponential backoff and giving up (MarkFailed) after policy.MaxAttempts.
+// It blocks until ctx is cancelled, and is meant to be run in its own
+// goroutine, one per Fleet server process.
+func (d *dispatcher) Run(ctx context.Context, pollInterval time.Duration) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.dispatchPending(ctx)
+		}
+	}
+}
+
+func (d *dispatcher) dispatchPending(ctx context.Context) {
+	pending, err := d.store.ListPendingMessages(ctx, 100)
+	if err != nil {
+		log.Error().Err(err).Msg("list pending courier messages")
+		return
+	}
+
+	now := time.Now()
+	for _, pm := range pending {
+		if pm.NextAttemptAt.After(now) {
+			continue
+		}
+		d.attempt(ctx, pm)
+	}
+}
+
+func (d *dispatcher) attempt(ctx context.Context, pm *PendingMessage) {
+	provider, ok := d.providers[pm.Message.Channel]
+	if !ok {
+		log.Error().Str("channel", string(pm.Message.Channel)).Msg("no provider for queued message, dropping")
+		if err := d.store.MarkFailed(ctx, pm.ID); err != nil {
+			log.Error().Err(err).Msg("mark courier message failed")
+		}
+		return
+	}
+
+	err := provider.Send(ctx, pm.Message)
+	if err == nil {
+		if err := d.store.MarkDelivered(ctx, pm.ID); err != nil {
+			log.Error().Err(err).Msg("mark courier message delivered")
+		}
+		return
+	}
+
+	attempts := pm.Attempts + 1
+	if attempts >= d.policy.MaxAttempts {
+		log.Error().Err(err).Int("attempts", attempts).Msg("courier message exhausted retries, giving up")
+		if err := d.store.MarkFailed(ctx, pm.ID); err != nil {
+			log.Error().Err(err).Msg("mark courier message failed")
+		}
+		return
+	}
+
+	next := time.Now().Add(backoffDelay(d.policy, attempts))
+	log.Debug().Err(err).Int("attempts", attempts).Time("next_attempt", next).Msg("courier send failed, will retry")
+	if err := d.store.MarkRetry(ctx, pm.ID, next); err != nil {
+		log.Error().Err(err).Msg("mark courier message for retry")
+	}
+}
+
+// backoffDelay returns the exponential backoff delay before the given
+// attempt number. The base interval is capped at policy.MaxInterval, but
+// the backoff package's default ±50% randomization is applied on top, so
+// the returned delay can reach 1.5x MaxInterval.
+func backoffDelay(policy RetryPolicy, attempt int) time.Duration {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = policy.InitialInterval
+	b.MaxInterval = policy.MaxInterval
+	b.Reset()
+
+	var d time.Duration
+	for i := 0; i < attempt; i++ {
+		d = b.NextBackOff()
+	}
+	return d
+}
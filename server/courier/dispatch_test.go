@@ -0,0 +1,140 @@
+package courier
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is an in-memory Store for tests.
+type memStore struct {
+	mu        sync.Mutex
+	nextID    uint
+	messages  map[uint]*PendingMessage
+	failed    map[uint]bool
+	delivered map[uint]bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		messages:  map[uint]*PendingMessage{},
+		failed:    map[uint]bool{},
+		delivered: map[uint]bool{},
+	}
+}
+
+func (m *memStore) QueueMessage(ctx context.Context, msg Message) (*PendingMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	pm := &PendingMessage{ID: m.nextID, Message: msg}
+	m.messages[pm.ID] = pm
+	return pm, nil
+}
+
+func (m *memStore) ListPendingMessages(ctx context.Context, limit int) ([]*PendingMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*PendingMessage
+	for _, pm := range m.messages {
+		if !m.delivered[pm.ID] && !m.failed[pm.ID] {
+			out = append(out, pm)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) MarkDelivered(ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delivered[id] = true
+	return nil
+}
+
+func (m *memStore) MarkRetry(ctx context.Context, id uint, nextAttemptAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pm := m.messages[id]
+	pm.Attempts++
+	pm.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (m *memStore) MarkFailed(ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed[id] = true
+	return nil
+}
+
+// fakeProvider records every Send call and returns err (if non-nil) on
+// every attempt.
+type fakeProvider struct {
+	channel Channel
+	err     error
+	sent    []Message
+}
+
+func (p *fakeProvider) Channel() Channel { return p.channel }
+
+func (p *fakeProvider) Send(ctx context.Context, msg Message) error {
+	p.sent = append(p.sent, msg)
+	return p.err
+}
+
+func TestDispatcherSendRequiresConfiguredProvider(t *testing.T) {
+	store := newMemStore()
+	d := NewDispatcher(store, nil, DefaultRetryPolicy)
+
+	err := d.Send(context.Background(), Message{Channel: ChannelEmail, To: "a@example.com"})
+	require.Error(t, err)
+}
+
+func TestDispatcherDeliversSuccessfully(t *testing.T) {
+	store := newMemStore()
+	provider := &fakeProvider{channel: ChannelEmail}
+	d := NewDispatcher(store, []Provider{provider}, DefaultRetryPolicy)
+
+	require.NoError(t, d.Send(context.Background(), Message{Channel: ChannelEmail, To: "a@example.com"}))
+
+	dd := d.(*dispatcher)
+	dd.dispatchPending(context.Background())
+
+	require.Len(t, provider.sent, 1)
+	require.True(t, store.delivered[1])
+}
+
+func TestDispatcherRetriesOnFailureThenGivesUp(t *testing.T) {
+	store := newMemStore()
+	provider := &fakeProvider{channel: ChannelEmail, err: errors.New("smtp down")}
+	policy := RetryPolicy{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, MaxAttempts: 2}
+	d := NewDispatcher(store, []Provider{provider}, policy)
+
+	require.NoError(t, d.Send(context.Background(), Message{Channel: ChannelEmail, To: "a@example.com"}))
+
+	dd := d.(*dispatcher)
+	dd.dispatchPending(context.Background())
+	require.False(t, store.failed[1], "first failure should be scheduled for retry, not given up on")
+	require.Equal(t, 1, store.messages[1].Attempts)
+
+	// Make the message immediately eligible for its retry and dispatch again.
+	store.messages[1].NextAttemptAt = time.Time{}
+	dd.dispatchPending(context.Background())
+	require.True(t, store.failed[1], "message should be marked failed once MaxAttempts is reached")
+}
+
+func TestDispatcherDropsMessageWithNoProvider(t *testing.T) {
+	store := newMemStore()
+	d := NewDispatcher(store, nil, DefaultRetryPolicy)
+
+	pm, err := store.QueueMessage(context.Background(), Message{Channel: ChannelSMS, To: "+15555550100"})
+	require.NoError(t, err)
+
+	dd := d.(*dispatcher)
+	dd.attempt(context.Background(), pm)
+	require.True(t, store.failed[pm.ID])
+}
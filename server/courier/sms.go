@@ -0,0 +1,178 @@
+package courier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"text/template"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/config"
+)
+
+// TwilioConfig holds the credentials and sender number used by
+// NewTwilioProvider.
+type TwilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	FromNumber string
+}
+
+// twilioProvider sends ChannelSMS messages through the Twilio Messages API.
+type twilioProvider struct {
+	cfg    TwilioConfig
+	client *http.Client
+}
+
+// NewTwilioProvider returns a Provider that sends SMS through Twilio.
+func NewTwilioProvider(cfg TwilioConfig) Provider {
+	return &twilioProvider{cfg: cfg, client: fleethttp.NewClient()}
+}
+
+func (p *twilioProvider) Channel() Channel {
+	return ChannelSMS
+}
+
+func (p *twilioProvider) Send(ctx context.Context, msg Message) error {
+	endpoint := fmt.Sprintf("https://api.twilio.com/2010-04-01/Accounts/%s/Messages.json", p.cfg.AccountSID)
+
+	form := url.Values{}
+	form.Set("To", msg.To)
+	form.Set("From", p.cfg.FromNumber)
+	form.Set("Body", msg.Body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("build twilio request: %w", err)
+	}
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send twilio request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("twilio request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WebhookConfig configures a generic HTTP webhook SMS provider, for
+// on-prem/self-hosted SMS gateways that don't speak the Twilio API.
+type WebhookConfig struct {
+	URL string
+	// RequestTemplate is a Go text/template rendering the JSON request body
+	// sent to URL, with access to {{.To}} and {{.Body}}. Both must be piped
+	// through the "json" template func (e.g. {{.Body | json}}) so that a
+	// message containing quotes, backslashes, or newlines is JSON-escaped
+	// instead of producing invalid JSON or breaking out of its string into
+	// sibling keys.
+	RequestTemplate string
+	Headers         map[string]string
+}
+
+// webhookProvider sends ChannelSMS messages by POSTing a templated JSON
+// body to a configured URL.
+type webhookProvider struct {
+	cfg    WebhookConfig
+	tmpl   *template.Template
+	client *http.Client
+}
+
+// NewWebhookProvider returns a Provider that sends SMS by POSTing to an
+// arbitrary HTTP endpoint, rendering cfg.RequestTemplate for the body.
+func NewWebhookProvider(cfg WebhookConfig) (Provider, error) {
+	tmpl, err := template.New("webhook-sms").Funcs(template.FuncMap{
+		"json": jsonTemplateString,
+	}).Parse(cfg.RequestTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook request template: %w", err)
+	}
+	return &webhookProvider{cfg: cfg, tmpl: tmpl, client: fleethttp.NewClient()}, nil
+}
+
+// jsonTemplateString renders v as a JSON string literal, including the
+// surrounding quotes, for use as the "json" func in a WebhookConfig
+// template so substituted values can't produce invalid JSON or break out of
+// their string into sibling keys.
+func jsonTemplateString(v string) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("json-escape template value: %w", err)
+	}
+	return string(b), nil
+}
+
+func (p *webhookProvider) Channel() Channel {
+	return ChannelSMS
+}
+
+func (p *webhookProvider) Send(ctx context.Context, msg Message) error {
+	var body bytes.Buffer
+	if err := p.tmpl.Execute(&body, struct {
+		To   string
+		Body string
+	}{To: msg.To, Body: msg.Body}); err != nil {
+		return fmt.Errorf("render webhook request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, &body)
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// decodeRequestConfig unmarshals a provider's raw JSON configuration (as
+// stored in config.SMSConfig.RequestConfig) into dst.
+func decodeRequestConfig(raw json.RawMessage, dst interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, dst)
+}
+
+// NewProviderFromConfig builds the SMS Provider selected by cfg.Provider
+// ("twilio" or "webhook"), decoding cfg.RequestConfig into that provider's
+// own config struct. Returns a nil Provider and nil error if cfg.Provider is
+// empty, meaning SMS delivery is disabled.
+func NewProviderFromConfig(cfg config.SMSConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "twilio":
+		var twilioCfg TwilioConfig
+		if err := decodeRequestConfig(cfg.RequestConfig, &twilioCfg); err != nil {
+			return nil, fmt.Errorf("decode twilio sms config: %w", err)
+		}
+		return NewTwilioProvider(twilioCfg), nil
+	case "webhook":
+		var webhookCfg WebhookConfig
+		if err := decodeRequestConfig(cfg.RequestConfig, &webhookCfg); err != nil {
+			return nil, fmt.Errorf("decode webhook sms config: %w", err)
+		}
+		return NewWebhookProvider(webhookCfg)
+	default:
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+	}
+}
@@ -0,0 +1,67 @@
+package courier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookProviderEscapesBodyIntoValidJSON(t *testing.T) {
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p, err := NewWebhookProvider(WebhookConfig{
+		URL:             srv.URL,
+		RequestTemplate: `{"to": {{.To | json}}, "body": {{.Body | json}}}`,
+	})
+	require.NoError(t, err)
+
+	msg := Message{To: "+15550100", Body: "alert: host \"db-01\"\\injection: {\"evil\":true}\nline two"}
+	require.NoError(t, p.Send(context.Background(), msg))
+
+	require.Equal(t, msg.To, gotBody["to"])
+	require.Equal(t, msg.Body, gotBody["body"])
+}
+
+func TestNewProviderFromConfig(t *testing.T) {
+	t.Run("empty provider disables SMS", func(t *testing.T) {
+		p, err := NewProviderFromConfig(config.SMSConfig{})
+		require.NoError(t, err)
+		require.Nil(t, p)
+	})
+
+	t.Run("twilio", func(t *testing.T) {
+		raw, err := json.Marshal(TwilioConfig{AccountSID: "AC123", AuthToken: "secret", FromNumber: "+15550100"})
+		require.NoError(t, err)
+
+		p, err := NewProviderFromConfig(config.SMSConfig{Provider: "twilio", RequestConfig: raw})
+		require.NoError(t, err)
+		require.Equal(t, ChannelSMS, p.Channel())
+		require.IsType(t, &twilioProvider{}, p)
+		require.Equal(t, "AC123", p.(*twilioProvider).cfg.AccountSID)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		raw, err := json.Marshal(WebhookConfig{URL: "https://example.com/sms", RequestTemplate: `{"to": {{.To | json}}}`})
+		require.NoError(t, err)
+
+		p, err := NewProviderFromConfig(config.SMSConfig{Provider: "webhook", RequestConfig: raw})
+		require.NoError(t, err)
+		require.Equal(t, ChannelSMS, p.Channel())
+		require.IsType(t, &webhookProvider{}, p)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := NewProviderFromConfig(config.SMSConfig{Provider: "carrier-pigeon"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,41 @@
+package courier
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// smtpProvider sends ChannelEmail messages through the existing
+// fleet.MailService (e.g. Fleet's SMTP implementation), so Courier can be
+// introduced without having to rewrite how email is actually delivered.
+type smtpProvider struct {
+	mailer fleet.MailService
+}
+
+// NewSMTPProvider returns a Provider that sends email through mailer.
+func NewSMTPProvider(mailer fleet.MailService) Provider {
+	return &smtpProvider{mailer: mailer}
+}
+
+func (p *smtpProvider) Channel() Channel {
+	return ChannelEmail
+}
+
+func (p *smtpProvider) Send(ctx context.Context, msg Message) error {
+	return p.mailer.SendEmail(fleet.Email{
+		Subject: msg.Subject,
+		To:      []string{msg.To},
+		Mailer:  &rawMailer{body: []byte(msg.Body)},
+	})
+}
+
+// rawMailer adapts an already-rendered body to the fleet.Mailer interface
+// expected by fleet.Email.
+type rawMailer struct {
+	body []byte
+}
+
+func (m *rawMailer) Message() ([]byte, error) {
+	return m.body, nil
+}
@@ -0,0 +1,17 @@
+package filestore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockFileBackendConformance(t *testing.T) {
+	RunConformanceTests(t, NewMockFileBackend())
+}
+
+func TestLocalFileBackendConformance(t *testing.T) {
+	backend, err := NewLocalFileBackend(t.TempDir(), 0o755)
+	require.NoError(t, err)
+	RunConformanceTests(t, backend)
+}
@@ -0,0 +1,103 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunConformanceTests exercises the FileBackend contract against backend,
+// so every implementation (local, S3, mock) is held to the same behavior.
+// Call it from a backend-specific _test.go as `filestore.RunConformanceTests(t, backend)`.
+func RunConformanceTests(t *testing.T, backend FileBackend) {
+	ctx := context.Background()
+
+	t.Run("write and read round-trip", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "a/b.txt", bytes.NewBufferString("hello")))
+
+		r, err := backend.ReadFile(ctx, "a/b.txt")
+		require.NoError(t, err)
+		defer r.Close()
+
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(got))
+	})
+
+	t.Run("file exists", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "exists.txt", bytes.NewBufferString("x")))
+
+		exists, err := backend.FileExists(ctx, "exists.txt")
+		require.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = backend.FileExists(ctx, "does-not-exist.txt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("copy file", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "src.txt", bytes.NewBufferString("copy me")))
+		require.NoError(t, backend.CopyFile(ctx, "src.txt", "dst.txt"))
+
+		r, err := backend.ReadFile(ctx, "dst.txt")
+		require.NoError(t, err)
+		defer r.Close()
+
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "copy me", string(got))
+	})
+
+	t.Run("move file", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "move-src.txt", bytes.NewBufferString("move me")))
+		require.NoError(t, backend.MoveFile(ctx, "move-src.txt", "move-dst.txt"))
+
+		exists, err := backend.FileExists(ctx, "move-src.txt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+
+		r, err := backend.ReadFile(ctx, "move-dst.txt")
+		require.NoError(t, err)
+		defer r.Close()
+	})
+
+	t.Run("remove file", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "remove-me.txt", bytes.NewBufferString("x")))
+		require.NoError(t, backend.RemoveFile(ctx, "remove-me.txt"))
+
+		exists, err := backend.FileExists(ctx, "remove-me.txt")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("list directory", func(t *testing.T) {
+		require.NoError(t, backend.WriteFile(ctx, "dir/one.txt", bytes.NewBufferString("1")))
+		require.NoError(t, backend.WriteFile(ctx, "dir/two.txt", bytes.NewBufferString("2")))
+
+		infos, err := backend.ListDirectory(ctx, "dir")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, len(infos), 2)
+
+		// Name must be the full key relative to the backend root, not the
+		// bare basename, so ListDirectory's output can be passed straight
+		// to ReadFile without the caller re-joining it onto the directory.
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+		assert.Contains(t, names, "dir/one.txt")
+		assert.Contains(t, names, "dir/two.txt")
+
+		r, err := backend.ReadFile(ctx, "dir/one.txt")
+		require.NoError(t, err)
+		defer r.Close()
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(got))
+	})
+}
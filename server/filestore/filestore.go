@@ -0,0 +1,37 @@
+// Package filestore provides a storage-backend-agnostic abstraction used to
+// spool large osquery result batches before shipping them to a log
+// destination, store MDM configuration profiles and software installer
+// packages, and export query results as downloadable artifacts.
+package filestore
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// FileInfo describes an entry returned by FileBackend.ListDirectory.
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// FileBackend is implemented by each storage backend filestore supports.
+// Paths are always slash-separated and relative to the backend's root
+// (a directory for LocalFileBackend, a bucket for S3FileBackend).
+type FileBackend interface {
+	ReadFile(ctx context.Context, path string) (io.ReadCloser, error)
+	WriteFile(ctx context.Context, path string, r io.Reader) error
+	RemoveFile(ctx context.Context, path string) error
+	FileExists(ctx context.Context, path string) (bool, error)
+	ListDirectory(ctx context.Context, path string) ([]FileInfo, error)
+	MoveFile(ctx context.Context, oldPath, newPath string) error
+	CopyFile(ctx context.Context, srcPath, dstPath string) error
+	// PresignURL returns a time-limited URL that can be used to download
+	// path without further authentication, for backends that support it
+	// (S3FileBackend). LocalFileBackend returns an error, since serving a
+	// presigned local file URL is the caller's responsibility.
+	PresignURL(ctx context.Context, path string, expiresIn time.Duration) (string, error)
+}
@@ -0,0 +1,173 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalFileBackend implements FileBackend on top of a directory on the
+// local filesystem.
+type LocalFileBackend struct {
+	root    string
+	dirMode os.FileMode
+}
+
+// NewLocalFileBackend creates a LocalFileBackend rooted at root, creating it
+// (and any missing parents) with dirMode if it doesn't already exist.
+func NewLocalFileBackend(root string, dirMode os.FileMode) (*LocalFileBackend, error) {
+	if err := os.MkdirAll(root, dirMode); err != nil {
+		return nil, fmt.Errorf("create root %q: %w", root, err)
+	}
+	return &LocalFileBackend{root: root, dirMode: dirMode}, nil
+}
+
+// resolve joins path onto the backend root, guarding against path traversal
+// escaping the root (e.g. "../../etc/passwd").
+func (b *LocalFileBackend) resolve(path string) (string, error) {
+	full := filepath.Join(b.root, filepath.FromSlash(path))
+	rel, err := filepath.Rel(b.root, full)
+	if err != nil {
+		return "", fmt.Errorf("resolve %q: %w", path, err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes backend root: %q", path)
+	}
+	return full, nil
+}
+
+func (b *LocalFileBackend) ReadFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", path, err)
+	}
+	return f, nil
+}
+
+func (b *LocalFileBackend) WriteFile(ctx context.Context, path string, r io.Reader) error {
+	full, err := b.resolve(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), b.dirMode); err != nil {
+		return fmt.Errorf("mkdir for %q: %w", path, err)
+	}
+
+	tmp := full + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %q: %w", path, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("write %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("close %q: %w", path, err)
+	}
+	if err := os.Rename(tmp, full); err != nil {
+		return fmt.Errorf("rename %q: %w", path, err)
+	}
+	return nil
+}
+
+func (b *LocalFileBackend) RemoveFile(ctx context.Context, path string) error {
+	full, err := b.resolve(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil {
+		return fmt.Errorf("remove %q: %w", path, err)
+	}
+	return nil
+}
+
+func (b *LocalFileBackend) FileExists(ctx context.Context, path string) (bool, error) {
+	full, err := b.resolve(path)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(full)
+	switch {
+	case err == nil:
+		return true, nil
+	case os.IsNotExist(err):
+		return false, nil
+	default:
+		return false, fmt.Errorf("stat %q: %w", path, err)
+	}
+}
+
+func (b *LocalFileBackend) ListDirectory(ctx context.Context, dir string) ([]FileInfo, error) {
+	full, err := b.resolve(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(full)
+	if err != nil {
+		return nil, fmt.Errorf("read dir %q: %w", dir, err)
+	}
+
+	infos := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("stat %q: %w", e.Name(), err)
+		}
+		// Name is the full key relative to the backend root (matching
+		// S3FileBackend and MockFileBackend), not the bare basename, so
+		// callers can pass it straight to ReadFile/WriteFile without
+		// re-joining it onto dir themselves.
+		infos = append(infos, FileInfo{
+			Name:    path.Join(dir, e.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   e.IsDir(),
+		})
+	}
+	return infos, nil
+}
+
+func (b *LocalFileBackend) MoveFile(ctx context.Context, oldPath, newPath string) error {
+	oldFull, err := b.resolve(oldPath)
+	if err != nil {
+		return err
+	}
+	newFull, err := b.resolve(newPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(newFull), b.dirMode); err != nil {
+		return fmt.Errorf("mkdir for %q: %w", newPath, err)
+	}
+	if err := os.Rename(oldFull, newFull); err != nil {
+		return fmt.Errorf("move %q to %q: %w", oldPath, newPath, err)
+	}
+	return nil
+}
+
+func (b *LocalFileBackend) CopyFile(ctx context.Context, srcPath, dstPath string) error {
+	src, err := b.ReadFile(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return b.WriteFile(ctx, dstPath, src)
+}
+
+// PresignURL is not supported by LocalFileBackend; serving a downloadable
+// URL for a local file is the caller's responsibility.
+func (b *LocalFileBackend) PresignURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	return "", fmt.Errorf("presigned URLs are not supported by LocalFileBackend")
+}
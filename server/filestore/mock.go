@@ -0,0 +1,124 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MockFileBackend is an in-memory FileBackend, used in tests in place of
+// LocalFileBackend/S3FileBackend.
+type MockFileBackend struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMockFileBackend returns an empty MockFileBackend.
+func NewMockFileBackend() *MockFileBackend {
+	return &MockFileBackend{files: map[string][]byte{}}
+}
+
+func (b *MockFileBackend) ReadFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.files[path]
+	if !ok {
+		return nil, fmt.Errorf("file not found: %q", path)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *MockFileBackend) WriteFile(ctx context.Context, path string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.files[path] = data
+	return nil
+}
+
+func (b *MockFileBackend) RemoveFile(ctx context.Context, path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.files[path]; !ok {
+		return fmt.Errorf("file not found: %q", path)
+	}
+	delete(b.files, path)
+	return nil
+}
+
+func (b *MockFileBackend) FileExists(ctx context.Context, path string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.files[path]
+	return ok, nil
+}
+
+func (b *MockFileBackend) ListDirectory(ctx context.Context, path string) ([]FileInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	prefix := strings.TrimSuffix(path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var infos []FileInfo
+	for name, data := range b.files {
+		if strings.HasPrefix(name, prefix) {
+			infos = append(infos, FileInfo{Name: name, Size: int64(len(data))})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+func (b *MockFileBackend) MoveFile(ctx context.Context, oldPath, newPath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.files[oldPath]
+	if !ok {
+		return fmt.Errorf("file not found: %q", oldPath)
+	}
+	b.files[newPath] = data
+	delete(b.files, oldPath)
+	return nil
+}
+
+func (b *MockFileBackend) CopyFile(ctx context.Context, srcPath, dstPath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.files[srcPath]
+	if !ok {
+		return fmt.Errorf("file not found: %q", srcPath)
+	}
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	b.files[dstPath] = cp
+	return nil
+}
+
+// PresignURL returns a fake "mock://" URL, sufficient for tests asserting
+// that a URL was generated without depending on real S3 credentials.
+func (b *MockFileBackend) PresignURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.files[path]; !ok {
+		return "", fmt.Errorf("file not found: %q", path)
+	}
+	return fmt.Sprintf("mock://%s?expires_in=%s", path, expiresIn), nil
+}
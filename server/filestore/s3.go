@@ -0,0 +1,179 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3FileBackendConfig configures S3FileBackend.
+type S3FileBackendConfig struct {
+	Bucket           string
+	Region           string
+	Endpoint         string
+	StsAssumeRoleArn string
+	// ServerSideEncryption is the SSE mode applied on WriteFile (e.g.
+	// "AES256" or "aws:kms"). Empty disables SSE.
+	ServerSideEncryption string
+	// ForcePathStyle uses path-style addressing (bucket in the path) rather
+	// than virtual-hosted-style (bucket in the host), needed for most
+	// S3-compatible endpoints.
+	ForcePathStyle bool
+}
+
+// S3FileBackend implements FileBackend on top of an S3 (or S3-compatible)
+// bucket.
+type S3FileBackend struct {
+	cfg    S3FileBackendConfig
+	client *s3.S3
+}
+
+// NewS3FileBackend creates an S3FileBackend from cfg.
+func NewS3FileBackend(cfg S3FileBackendConfig) (*S3FileBackend, error) {
+	awsConf := aws.NewConfig().
+		WithRegion(cfg.Region).
+		WithS3ForcePathStyle(cfg.ForcePathStyle)
+	if cfg.Endpoint != "" {
+		awsConf = awsConf.WithEndpoint(cfg.Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, fmt.Errorf("create aws session: %w", err)
+	}
+
+	if cfg.StsAssumeRoleArn != "" {
+		creds := stscreds.NewCredentials(sess, cfg.StsAssumeRoleArn)
+		awsConf = awsConf.WithCredentials(creds)
+	}
+
+	return &S3FileBackend{cfg: cfg, client: s3.New(sess, awsConf)}, nil
+}
+
+func (b *S3FileBackend) ReadFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	out, err := b.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get object %q: %w", path, err)
+	}
+	return out.Body, nil
+}
+
+func (b *S3FileBackend) WriteFile(ctx context.Context, path string, r io.Reader) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(path),
+		Body:   aws.ReadSeekCloser(r),
+	}
+	if b.cfg.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(b.cfg.ServerSideEncryption)
+	}
+	if _, err := b.client.PutObjectWithContext(ctx, input); err != nil {
+		return fmt.Errorf("put object %q: %w", path, err)
+	}
+	return nil
+}
+
+func (b *S3FileBackend) RemoveFile(ctx context.Context, path string) error {
+	if _, err := b.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(path),
+	}); err != nil {
+		return fmt.Errorf("delete object %q: %w", path, err)
+	}
+	return nil
+}
+
+func (b *S3FileBackend) FileExists(ctx context.Context, path string) (bool, error) {
+	_, err := b.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		if isNotFoundErr(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("head object %q: %w", path, err)
+	}
+	return true, nil
+}
+
+func (b *S3FileBackend) ListDirectory(ctx context.Context, path string) ([]FileInfo, error) {
+	prefix := path
+	if prefix != "" && prefix[len(prefix)-1] != '/' {
+		prefix += "/"
+	}
+
+	var infos []FileInfo
+	err := b.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(b.cfg.Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, cp := range page.CommonPrefixes {
+			infos = append(infos, FileInfo{Name: aws.StringValue(cp.Prefix), IsDir: true})
+		}
+		for _, obj := range page.Contents {
+			infos = append(infos, FileInfo{
+				Name:    aws.StringValue(obj.Key),
+				Size:    aws.Int64Value(obj.Size),
+				ModTime: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list objects %q: %w", prefix, err)
+	}
+	return infos, nil
+}
+
+func (b *S3FileBackend) MoveFile(ctx context.Context, oldPath, newPath string) error {
+	if err := b.CopyFile(ctx, oldPath, newPath); err != nil {
+		return err
+	}
+	return b.RemoveFile(ctx, oldPath)
+}
+
+func (b *S3FileBackend) CopyFile(ctx context.Context, srcPath, dstPath string) error {
+	source := fmt.Sprintf("%s/%s", b.cfg.Bucket, srcPath)
+	if _, err := b.client.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(b.cfg.Bucket),
+		CopySource: aws.String(source),
+		Key:        aws.String(dstPath),
+	}); err != nil {
+		return fmt.Errorf("copy object %q to %q: %w", srcPath, dstPath, err)
+	}
+	return nil
+}
+
+// PresignURL returns a presigned GET URL for path, valid for expiresIn.
+func (b *S3FileBackend) PresignURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	req, _ := b.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(b.cfg.Bucket),
+		Key:    aws.String(path),
+	})
+	url, err := req.Presign(expiresIn)
+	if err != nil {
+		return "", fmt.Errorf("presign %q: %w", path, err)
+	}
+	return url, nil
+}
+
+func isNotFoundErr(err error) bool {
+	type awsError interface {
+		Code() string
+	}
+	if ae, ok := err.(awsError); ok {
+		return ae.Code() == s3.ErrCodeNoSuchKey || ae.Code() == "NotFound"
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// kafkaLogWriter produces osquery status/result log lines to a Kafka topic,
+// keying each message by host_identifier so records for the same host land
+// on the same partition.
+type kafkaLogWriter struct {
+	logger kitlog.Logger
+	writer *kafka.Writer
+}
+
+// NewKafkaLogWriter creates a JSONLogger that produces to topic using
+// conf.Brokers.
+func NewKafkaLogWriter(conf config.KafkaConfig, topic string, logger kitlog.Logger) *kafkaLogWriter {
+	w := &kafka.Writer{
+		Addr:     kafka.TCP(conf.Brokers...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+		Transport: &kafka.Transport{
+			ClientID: conf.ClientID,
+		},
+	}
+	return &kafkaLogWriter{logger: logger, writer: w}
+}
+
+// Write produces each log line as its own Kafka message, keyed by
+// host_identifier when present so messages for a given host are ordered and
+// land on the same partition.
+func (w *kafkaLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	messages := make([]kafka.Message, 0, len(logs))
+	for _, l := range logs {
+		msg := kafka.Message{Value: l}
+		// Leave Key nil when there is no host_identifier: the Hash balancer
+		// round-robins nil keys but hashes an empty, non-nil one.
+		if key := hostIdentifierKey(l); key != "" {
+			msg.Key = []byte(key)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := w.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("kafka produce: %w", err)
+	}
+
+	level.Debug(w.logger).Log("msg", "produced logs to kafka", "topic", w.writer.Topic, "count", len(messages))
+	return nil
+}
+
+// hostIdentifierKey extracts the "host_identifier" field from a raw osquery
+// log line, used as the Kafka message key for partition affinity. Returns
+// empty if absent, which kafka-go's Hash balancer treats as unkeyed.
+func hostIdentifierKey(raw json.RawMessage) string {
+	var fields struct {
+		HostIdentifier string `json:"host_identifier"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return ""
+	}
+	return fields.HostIdentifier
+}
@@ -0,0 +1,26 @@
+package logging
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostIdentifierKey(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"present", `{"host_identifier":"abc-123","other":"x"}`, "abc-123"},
+		{"absent", `{"other":"x"}`, ""},
+		{"invalid json", `not json`, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.want, hostIdentifierKey(json.RawMessage(c.raw)))
+		})
+	}
+}
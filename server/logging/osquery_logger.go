@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// OsqueryLogWriter is implemented by every osquery status/result log
+// plugin (filesystem, the cloud log stream plugins, s3LogWriter,
+// kafkaLogWriter, ...).
+type OsqueryLogWriter interface {
+	Write(ctx context.Context, logs []json.RawMessage) error
+}
+
+// OsqueryLogger holds the writers osquery status and result logs are sent
+// to, which may be different plugins (e.g. status to filesystem, result to
+// s3).
+type OsqueryLogger struct {
+	Status OsqueryLogWriter
+	Result OsqueryLogWriter
+}
+
+// NewOsqueryLogger builds the OsqueryLogger used to persist osquery status
+// and result logs, selecting the result/status plugin independently based
+// on conf.Osquery.ResultLogPlugin / conf.Osquery.StatusLogPlugin.
+func NewOsqueryLogger(conf config.FleetConfig, logger kitlog.Logger) (*OsqueryLogger, error) {
+	result, err := newPluginLogWriter(conf, logger, conf.Osquery.ResultLogPlugin, "result")
+	if err != nil {
+		return nil, fmt.Errorf("create result log writer: %w", err)
+	}
+	status, err := newPluginLogWriter(conf, logger, conf.Osquery.StatusLogPlugin, "status")
+	if err != nil {
+		return nil, fmt.Errorf("create status log writer: %w", err)
+	}
+	return &OsqueryLogger{Result: result, Status: status}, nil
+}
+
+// newPluginLogWriter constructs the log plugin named by plugin ("filesystem",
+// "kinesis", "firehose", "lambda", "pubsub", "stdout", "s3", "kafka").
+// logType is "result" or "status", used to pick the matching config stream/
+// topic/file name and to label logs.
+func newPluginLogWriter(conf config.FleetConfig, logger kitlog.Logger, plugin, logType string) (OsqueryLogWriter, error) {
+	switch plugin {
+	case "", "filesystem":
+		path := conf.Filesystem.ResultLogFile
+		if logType == "status" {
+			path = conf.Filesystem.StatusLogFile
+		}
+		return NewFilesystemLogWriter(path, logger, conf.Filesystem.EnableLogRotation, conf.Filesystem.EnableLogCompression)
+	case "s3":
+		return NewS3LogWriter(conf.S3, logger, logType)
+	case "kafka":
+		topic := conf.Kafka.ResultTopic
+		if logType == "status" {
+			topic = conf.Kafka.StatusTopic
+		}
+		return NewKafkaLogWriter(conf.Kafka, topic, logger), nil
+	case "kinesis":
+		return NewKinesisLogWriter(conf.Kinesis, logger, logType)
+	case "firehose":
+		return NewFirehoseLogWriter(conf.Firehose, logger, logType)
+	case "lambda":
+		return NewLambdaLogWriter(conf.Lambda, logger, logType)
+	case "pubsub":
+		return NewPubSubLogWriter(conf.PubSub, logger, logType)
+	case "stdout":
+		return NewStdoutLogWriter(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown osquery log plugin %q", plugin)
+	}
+}
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/fleetdm/fleet/v4/server/config"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+)
+
+// s3LogWriter batches osquery status/result log lines, grouped by host,
+// and periodically uploads each host's batch to S3 as its own
+// gzip-compressed newline-delimited JSON object keyed by
+// <prefix>/<yyyy>/<mm>/<dd>/<hh>/<nodeKey>-<uuid>.json.gz.
+type s3LogWriter struct {
+	logger  kitlog.Logger
+	client  *s3.S3
+	bucket  string
+	prefix  string
+	sse     string
+	logType string
+
+	batchSize     int
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	buf      map[string][][]byte // keyed by host_identifier, "" for logs missing one
+	bufCount int
+	flushed  time.Time
+}
+
+// NewS3LogWriter creates a JSONLogger that uploads batched logs to S3. Pass
+// logType "status" or "result" so separate writers can be created for each
+// (mirroring the Status/Result fields of OsqueryLogger).
+func NewS3LogWriter(conf config.S3LoggingConfig, logger kitlog.Logger, logType string) (*s3LogWriter, error) {
+	awsConf := aws.NewConfig().WithRegion(conf.Region)
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, fmt.Errorf("create aws session: %w", err)
+	}
+
+	if conf.StsAssumeRoleArn != "" {
+		creds := stscreds.NewCredentials(sess, conf.StsAssumeRoleArn)
+		awsConf = awsConf.WithCredentials(creds)
+	}
+
+	batchSize := conf.BatchSize
+	if batchSize == 0 {
+		batchSize = 500
+	}
+	flushInterval := conf.FlushInterval
+	if flushInterval == 0 {
+		flushInterval = 1 * time.Minute
+	}
+
+	return &s3LogWriter{
+		logger:        logger,
+		client:        s3.New(sess, awsConf),
+		bucket:        conf.Bucket,
+		prefix:        conf.Prefix,
+		sse:           conf.ServerSideEncryption,
+		logType:       logType,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		buf:           map[string][][]byte{},
+		flushed:       time.Now(),
+	}, nil
+}
+
+// Write appends logs to the current batch, grouped by host_identifier so
+// each host's logs can be flushed to their own object, flushing to S3 once
+// batchSize or flushInterval is exceeded.
+func (w *s3LogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, l := range logs {
+		nodeKey := hostIdentifierKey(l)
+		w.buf[nodeKey] = append(w.buf[nodeKey], l)
+		w.bufCount++
+	}
+
+	if w.bufCount < w.batchSize && time.Since(w.flushed) < w.flushInterval {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// flush uploads each host's batch as its own gzip object, keyed by that
+// host's node key, and resets the buffer. Callers must hold w.mu.
+func (w *s3LogWriter) flush(ctx context.Context) error {
+	if w.bufCount == 0 {
+		w.flushed = time.Now()
+		return nil
+	}
+
+	for nodeKey, lines := range w.buf {
+		if err := w.flushHost(ctx, nodeKey, lines); err != nil {
+			return err
+		}
+	}
+
+	w.buf = map[string][][]byte{}
+	w.bufCount = 0
+	w.flushed = time.Now()
+	return nil
+}
+
+// flushHost uploads lines, all belonging to nodeKey (or "" if the logs had
+// no host_identifier), as a single gzip object.
+func (w *s3LogWriter) flushHost(ctx context.Context, nodeKey string, lines [][]byte) error {
+	var body bytes.Buffer
+	gw := gzip.NewWriter(&body)
+	for _, l := range lines {
+		if _, err := gw.Write(l); err != nil {
+			return fmt.Errorf("gzip write: %w", err)
+		}
+		if _, err := gw.Write([]byte("\n")); err != nil {
+			return fmt.Errorf("gzip write: %w", err)
+		}
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("gzip close: %w", err)
+	}
+
+	key := w.objectKey(nodeKey)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(w.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body.Bytes()),
+	}
+	if w.sse != "" {
+		input.ServerSideEncryption = aws.String(w.sse)
+	}
+
+	if _, err := w.client.PutObjectWithContext(ctx, input); err != nil {
+		return fmt.Errorf("put object %q: %w", key, err)
+	}
+
+	level.Debug(w.logger).Log("msg", "flushed logs to s3", "key", key, "count", len(lines))
+	return nil
+}
+
+// objectKey returns <prefix>/<yyyy>/<mm>/<dd>/<hh>/<nodeKey>-<uuid>.json.gz.
+// nodeKey falls back to w.logType ("status"/"result") when the logs being
+// flushed had no host_identifier, so the key still identifies the stream.
+func (w *s3LogWriter) objectKey(nodeKey string) string {
+	if nodeKey == "" {
+		nodeKey = w.logType
+	}
+	now := time.Now().UTC()
+	return fmt.Sprintf("%s/%04d/%02d/%02d/%02d/%s-%s.json.gz",
+		w.prefix, now.Year(), now.Month(), now.Day(), now.Hour(), nodeKey, uuid.New().String())
+}
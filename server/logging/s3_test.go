@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var objectKeyPattern = regexp.MustCompile(`^osquery/\d{4}/\d{2}/\d{2}/\d{2}/result-[0-9a-f-]+\.json\.gz$`)
+
+func TestS3LogWriterObjectKeyFallsBackToLogType(t *testing.T) {
+	w := &s3LogWriter{prefix: "osquery", logType: "result"}
+	key := w.objectKey("")
+	require.Regexp(t, objectKeyPattern, key)
+}
+
+func TestS3LogWriterObjectKeyUsesNodeKey(t *testing.T) {
+	w := &s3LogWriter{prefix: "osquery", logType: "result"}
+	key := w.objectKey("abc123")
+	require.Regexp(t, regexp.MustCompile(`^osquery/\d{4}/\d{2}/\d{2}/\d{2}/abc123-[0-9a-f-]+\.json\.gz$`), key)
+}
+
+// TestS3LogWriterWriteGroupsByHostIdentifier asserts that Write buckets
+// incoming log lines by host_identifier rather than mixing every host into
+// one batch, which is what lets flush later upload one object per host.
+func TestS3LogWriterWriteGroupsByHostIdentifier(t *testing.T) {
+	w := &s3LogWriter{
+		prefix:        "osquery",
+		logType:       "result",
+		batchSize:     1000,
+		flushInterval: time.Hour,
+		buf:           map[string][][]byte{},
+		flushed:       time.Now(),
+	}
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"host_identifier":"host-a","foo":1}`),
+		json.RawMessage(`{"host_identifier":"host-b","foo":2}`),
+		json.RawMessage(`{"host_identifier":"host-a","foo":3}`),
+		json.RawMessage(`{"foo":4}`),
+	}
+	require.NoError(t, w.Write(nil, logs))
+
+	require.Len(t, w.buf["host-a"], 2)
+	require.Len(t, w.buf["host-b"], 1)
+	require.Len(t, w.buf[""], 1)
+	require.Equal(t, 4, w.bufCount)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/courier"
+	"github.com/fleetdm/fleet/v4/server/filestore"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/logging"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// courierDispatchPollInterval is how often NewService polls a courier.Runner
+// (e.g. the queued dispatcher from server/courier) for pending messages.
+const courierDispatchPollInterval = 30 * time.Second
+
+// Service is Fleet's core service implementation. It embeds fleet.Service so
+// that the (very large) set of business-logic methods implemented across
+// the rest of this package are promoted here unmodified; this file only
+// owns construction and the small number of cross-cutting concerns (mail,
+// file storage, signed error redirects) threaded through NewService.
+type Service struct {
+	fleet.Service
+
+	ds             fleet.Datastore
+	task           fleet.Datastore
+	resultStore    fleet.QueryResultStore
+	liveQueryStore fleet.LiveQueryStore
+	logger         kitlog.Logger
+	osqueryLogger  *logging.OsqueryLogger
+	config         config.FleetConfig
+	courier        courier.Courier
+	clock          clock.Clock
+	license        fleet.LicenseInfo
+	fileBackend    filestore.FileBackend
+	webErrorSigner crypto.Signer
+}
+
+// NewService constructs Fleet's core Service. courier replaces the previous
+// bare SMTP mailer parameter: if it implements courier.Runner (as
+// courier.NewDispatcher's result does), NewService starts its delivery loop
+// for the lifetime of the process. fileBackend is the server/filestore
+// backend used for large object storage, and webErrorSigner signs the
+// /error redirect URLs produced by RenderWebError. A signed URL stays valid
+// for as long as the signing key does; it is not limited to a single use.
+func NewService(
+	ds fleet.Datastore,
+	resultStore fleet.QueryResultStore,
+	logger kitlog.Logger,
+	osqueryLogger *logging.OsqueryLogger,
+	fleetConfig config.FleetConfig,
+	mailCourier courier.Courier,
+	c clock.Clock,
+	failingPolicySet interface{},
+	liveQueryStore fleet.LiveQueryStore,
+	task fleet.Datastore,
+	license fleet.LicenseInfo,
+	fileBackend filestore.FileBackend,
+	webErrorSigner crypto.Signer,
+) (fleet.Service, error) {
+	svc := &Service{
+		ds:             ds,
+		task:           task,
+		resultStore:    resultStore,
+		liveQueryStore: liveQueryStore,
+		logger:         logger,
+		osqueryLogger:  osqueryLogger,
+		config:         fleetConfig,
+		courier:        mailCourier,
+		clock:          c,
+		license:        license,
+		fileBackend:    fileBackend,
+		webErrorSigner: webErrorSigner,
+	}
+
+	if runner, ok := mailCourier.(courier.Runner); ok {
+		go runner.Run(context.Background(), courierDispatchPollInterval)
+	}
+
+	return svc, nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"github.com/fleetdm/fleet/v4/server/logging"
 	"net/http"
@@ -12,6 +13,8 @@ import (
 	"github.com/WatchBeam/clock"
 	eeservice "github.com/fleetdm/fleet/v4/ee/server/service"
 	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/courier"
+	"github.com/fleetdm/fleet/v4/server/filestore"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	kitlog "github.com/go-kit/kit/log"
@@ -27,7 +30,7 @@ func newTestService(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet.Live
 }
 
 func newTestServiceWithConfig(ds fleet.Datastore, fleetConfig config.FleetConfig, rs fleet.QueryResultStore, lq fleet.LiveQueryStore) fleet.Service {
-	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error { return nil }}
+	testCourier := &mockCourier{SendFn: func(ctx context.Context, msg courier.Message) error { return nil }}
 	license := fleet.LicenseInfo{Tier: "core"}
 	writer, err := logging.NewFilesystemLogWriter(
 		fleetConfig.Filesystem.StatusLogFile,
@@ -35,8 +38,10 @@ func newTestServiceWithConfig(ds fleet.Datastore, fleetConfig config.FleetConfig
 		fleetConfig.Filesystem.EnableLogRotation,
 		fleetConfig.Filesystem.EnableLogCompression,
 	)
-	osqlogger:= &logging.OsqueryLogger{Status: writer, Result: writer}
-	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, fleetConfig, mailer, clock.C, nil, lq, ds, license)
+	osqlogger := &logging.OsqueryLogger{Status: writer, Result: writer}
+	testFileBackend := filestore.NewMockFileBackend()
+	testWebErrorSigner := NewDeterministicWebErrorSigner()
+	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, fleetConfig, testCourier, clock.C, nil, lq, ds, license, testFileBackend, testWebErrorSigner)
 	if err != nil {
 		panic(err)
 	}
@@ -44,7 +49,7 @@ func newTestServiceWithConfig(ds fleet.Datastore, fleetConfig config.FleetConfig
 }
 
 func newTestBasicService(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet.LiveQueryStore) fleet.Service {
-	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error { return nil }}
+	testCourier := &mockCourier{SendFn: func(ctx context.Context, msg courier.Message) error { return nil }}
 	license := fleet.LicenseInfo{Tier: fleet.TierBasic}
 	testConfig := config.TestConfig()
 	writer, err := logging.NewFilesystemLogWriter(
@@ -53,12 +58,33 @@ func newTestBasicService(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet
 		testConfig.Filesystem.EnableLogRotation,
 		testConfig.Filesystem.EnableLogCompression,
 	)
-	osqlogger:= &logging.OsqueryLogger{Status: writer, Result: writer}
-	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, testConfig, mailer, clock.C, nil, lq, ds, license)
+	osqlogger := &logging.OsqueryLogger{Status: writer, Result: writer}
+	testFileBackend := filestore.NewMockFileBackend()
+	testWebErrorSigner := NewDeterministicWebErrorSigner()
+	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, testConfig, testCourier, clock.C, nil, lq, ds, license, testFileBackend, testWebErrorSigner)
 	if err != nil {
 		panic(err)
 	}
-	svc, err = eeservice.NewService(svc, ds, kitlog.NewNopLogger(), testConfig, mailer, clock.C, &license)
+	svc, err = eeservice.NewService(svc, ds, kitlog.NewNopLogger(), testConfig, testCourier, clock.C, &license)
+	if err != nil {
+		panic(err)
+	}
+	return svc
+}
+
+func newTestServiceWithFileBackend(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet.LiveQueryStore, fb filestore.FileBackend) fleet.Service {
+	testCourier := &mockCourier{SendFn: func(ctx context.Context, msg courier.Message) error { return nil }}
+	license := fleet.LicenseInfo{Tier: "core"}
+	testConfig := config.TestConfig()
+	writer, err := logging.NewFilesystemLogWriter(
+		testConfig.Filesystem.StatusLogFile,
+		kitlog.NewNopLogger(),
+		testConfig.Filesystem.EnableLogRotation,
+		testConfig.Filesystem.EnableLogCompression,
+	)
+	osqlogger := &logging.OsqueryLogger{Status: writer, Result: writer}
+	testWebErrorSigner := NewDeterministicWebErrorSigner()
+	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, testConfig, testCourier, clock.C, nil, lq, ds, license, fb, testWebErrorSigner)
 	if err != nil {
 		panic(err)
 	}
@@ -66,7 +92,7 @@ func newTestBasicService(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet
 }
 
 func newTestServiceWithClock(ds fleet.Datastore, rs fleet.QueryResultStore, lq fleet.LiveQueryStore, c clock.Clock) fleet.Service {
-	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error { return nil }}
+	testCourier := &mockCourier{SendFn: func(ctx context.Context, msg courier.Message) error { return nil }}
 	license := fleet.LicenseInfo{Tier: "core"}
 	testConfig := config.TestConfig()
 	writer, err := logging.NewFilesystemLogWriter(
@@ -75,8 +101,10 @@ func newTestServiceWithClock(ds fleet.Datastore, rs fleet.QueryResultStore, lq f
 		testConfig.Filesystem.EnableLogRotation,
 		testConfig.Filesystem.EnableLogCompression,
 	)
-	osqlogger:= &logging.OsqueryLogger{Status: writer, Result: writer}
-	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, testConfig, mailer, c, nil, lq, ds, license)
+	osqlogger := &logging.OsqueryLogger{Status: writer, Result: writer}
+	testFileBackend := filestore.NewMockFileBackend()
+	testWebErrorSigner := NewDeterministicWebErrorSigner()
+	svc, err := NewService(ds, rs, kitlog.NewNopLogger(), osqlogger, testConfig, testCourier, c, nil, lq, ds, license, testFileBackend, testWebErrorSigner)
 	if err != nil {
 		panic(err)
 	}
@@ -147,18 +175,24 @@ var testUsers = map[string]struct {
 	},
 }
 
-type mockMailService struct {
-	SendEmailFn func(e fleet.Email) error
-	Invoked     bool
+// mockCourier is a courier.Courier test double covering both the email and
+// SMS channels, replacing the old mockMailService now that NewService takes
+// a courier.Courier in place of a fleet.MailService.
+type mockCourier struct {
+	SendFn  func(ctx context.Context, msg courier.Message) error
+	Invoked bool
 }
 
-func (svc *mockMailService) SendEmail(e fleet.Email) error {
-	svc.Invoked = true
-	return svc.SendEmailFn(e)
+func (c *mockCourier) Send(ctx context.Context, msg courier.Message) error {
+	c.Invoked = true
+	return c.SendFn(ctx, msg)
 }
 
 type TestServerOpts struct {
 	Tier string
+	// FileBackend overrides the filestore.FileBackend used by the service
+	// under test, e.g. with filestore.NewMockFileBackend().
+	FileBackend filestore.FileBackend
 }
 
 func RunServerForTestsWithDS(t *testing.T, ds fleet.Datastore, opts ...TestServerOpts) (map[string]fleet.User, *httptest.Server) {
@@ -169,7 +203,12 @@ func RunServerForTestsWithDS(t *testing.T, ds fleet.Datastore, opts ...TestServe
 			newServiceFunc = newTestBasicService
 		}
 	}
-	svc := newServiceFunc(ds, nil, nil)
+	var svc fleet.Service
+	if opts != nil && len(opts) > 0 && opts[0].FileBackend != nil {
+		svc = newTestServiceWithFileBackend(ds, nil, nil, opts[0].FileBackend)
+	} else {
+		svc = newServiceFunc(ds, nil, nil)
+	}
 	users := createTestUsers(t, ds)
 	logger := kitlog.NewLogfmtLogger(os.Stdout)
 
@@ -192,7 +231,25 @@ func RunServerForTestsWithDS(t *testing.T, ds fleet.Datastore, opts ...TestServe
 		fmt.Fprint(w, "index")
 	}))
 
-	server := httptest.NewServer(r)
+	// svc may be wrapped (e.g. TierBasic runs it through eeservice.NewService),
+	// which doesn't promote *Service's extra methods. Every test constructor
+	// in this file signs with the same deterministic key, so falling back to
+	// a throwaway *Service carrying that key is equivalent to unwrapping svc.
+	coreSvc, ok := svc.(*Service)
+	if !ok {
+		coreSvc = &Service{webErrorSigner: NewDeterministicWebErrorSigner()}
+	}
+	pubKeyHandler, err := coreSvc.ErrorsPubKeyHandler()
+	if err != nil {
+		panic(err)
+	}
+	r.Handle("/api/v1/fleet/errors/pubkey", pubKeyHandler)
+	errorHandler, err := coreSvc.VerifyWebErrorMiddleware(r)
+	if err != nil {
+		panic(err)
+	}
+
+	server := httptest.NewServer(errorHandler)
 	return users, server
 }
 
@@ -238,8 +295,8 @@ func testLambdaPluginConfig() config.FleetConfig {
 		AccessKeyID:      "foo",
 		SecretAccessKey:  "bar",
 		StsAssumeRoleArn: "baz",
-		ResultFunction: "result-func",
-		StatusFunction: "status-func",
+		ResultFunction:   "result-func",
+		StatusFunction:   "status-func",
 	}
 	return c
 }
@@ -264,4 +321,36 @@ func testStdoutPluginConfig() config.FleetConfig {
 	c.Osquery.ResultLogPlugin = "stdout"
 	c.Osquery.StatusLogPlugin = "stdout"
 	return c
-}
\ No newline at end of file
+}
+
+func testS3PluginConfig() config.FleetConfig {
+	c := config.TestConfig()
+	c.Filesystem = config.FilesystemConfig{}
+	c.Osquery.ResultLogPlugin = "s3"
+	c.Osquery.StatusLogPlugin = "s3"
+	c.S3 = config.S3LoggingConfig{
+		Region:               "us-east-1",
+		Bucket:               "test-bucket",
+		Prefix:               "osquery",
+		AccessKeyID:          "foo",
+		SecretAccessKey:      "bar",
+		StsAssumeRoleArn:     "baz",
+		ServerSideEncryption: "AES256",
+		BatchSize:            500,
+	}
+	return c
+}
+
+func testKafkaPluginConfig() config.FleetConfig {
+	c := config.TestConfig()
+	c.Filesystem = config.FilesystemConfig{}
+	c.Osquery.ResultLogPlugin = "kafka"
+	c.Osquery.StatusLogPlugin = "kafka"
+	c.Kafka = config.KafkaConfig{
+		Brokers:     []string{"localhost:9092"},
+		StatusTopic: "test-status-topic",
+		ResultTopic: "test-result-topic",
+		ClientID:    "fleet",
+	}
+	return c
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// webErrorPath is the React route that renders server-surfaced errors (SSO
+// failures, MDM enrollment callbacks, etc). Params redirected here are
+// signed by RenderWebError and checked by VerifyWebErrorSignature so a
+// crafted redirect can't inject an arbitrary error message into the UI.
+const webErrorPath = "/error"
+
+// webErrorSigParam is the query parameter carrying the base64-encoded
+// signature over the rest of the query string.
+const webErrorSigParam = "s"
+
+// NewWebErrorSigner generates a fresh Ed25519 signing key for signing
+// /error redirects. It's called once at startup; the key only needs to
+// live as long as the process, since forged links are only useful against
+// the process that issued them.
+func NewWebErrorSigner() (crypto.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
+
+// NewDeterministicWebErrorSigner returns an Ed25519 signer derived from a
+// fixed seed, for tests that need reproducible signatures rather than a
+// fresh key on every run.
+func NewDeterministicWebErrorSigner() crypto.Signer {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// webErrorPublicKey extracts the ed25519.PublicKey from a crypto.Signer
+// produced by this package.
+func webErrorPublicKey(signer crypto.Signer) (ed25519.PublicKey, error) {
+	pub, ok := signer.Public().(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("web error signer is not an Ed25519 key")
+	}
+	return pub, nil
+}
+
+// signWebErrorParams signs SHA256(webErrorPath + "?" + params.Encode())
+// with signer, returning the base64 (URL encoding, no padding) signature.
+func signWebErrorParams(signer crypto.Signer, params url.Values) (string, error) {
+	digest := sha256.Sum256([]byte(webErrorPath + "?" + params.Encode()))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.Hash(0))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyWebErrorParams reports whether sig is a valid signature (as
+// produced by signWebErrorParams) over params under pub.
+func verifyWebErrorParams(pub ed25519.PublicKey, params url.Values, sig string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(webErrorPath + "?" + params.Encode()))
+	return ed25519.Verify(pub, digest[:], raw)
+}
+
+// RenderWebError redirects the browser to the web error page with params
+// URL-encoded in the query string, signed so that VerifyWebErrorSignature
+// (mounted in front of the /error route) can reject tampered or forged
+// redirects.
+func RenderWebError(w http.ResponseWriter, r *http.Request, signer crypto.Signer, status int, params url.Values) {
+	params = cloneURLValues(params)
+	params.Del(webErrorSigParam)
+
+	sig, err := signWebErrorParams(signer, params)
+	if err != nil {
+		http.Error(w, "sign error redirect", http.StatusInternalServerError)
+		return
+	}
+	params.Set(webErrorSigParam, sig)
+	http.Redirect(w, r, webErrorPath+"?"+params.Encode(), status)
+}
+
+func cloneURLValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		cp := make([]string, len(vs))
+		copy(cp, vs)
+		out[k] = cp
+	}
+	return out
+}
+
+// VerifyWebErrorSignature wraps next with a check that requests to
+// /error carry a valid signature produced by RenderWebError for the
+// same query string, returning 400 if the signature is missing or
+// invalid.
+func VerifyWebErrorSignature(signer crypto.Signer, next http.Handler) (http.Handler, error) {
+	pub, err := webErrorPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != webErrorPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		sig := q.Get(webErrorSigParam)
+		if sig == "" {
+			http.Error(w, "missing error signature", http.StatusBadRequest)
+			return
+		}
+		q.Del(webErrorSigParam)
+		if !verifyWebErrorParams(pub, q, sig) {
+			http.Error(w, "invalid error signature", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+// ErrorsPubKeyHandler returns the handler for
+// /api/v1/fleet/errors/pubkey, serving the public key matching the signer
+// passed to NewService. Mount it alongside the rest of the Fleet API
+// routes (attachFleetAPIRoutes) so RenderWebError's signatures can be
+// verified independently of this process.
+func (svc *Service) ErrorsPubKeyHandler() (http.HandlerFunc, error) {
+	return webErrorPubKeyHandler(svc.webErrorSigner)
+}
+
+// VerifyWebErrorMiddleware wraps next with the /error signature check
+// described on VerifyWebErrorSignature, using the signer passed to
+// NewService. Mount it in front of the router serving /error.
+func (svc *Service) VerifyWebErrorMiddleware(next http.Handler) (http.Handler, error) {
+	return VerifyWebErrorSignature(svc.webErrorSigner, next)
+}
+
+// webErrorPubKeyHandler serves the base64-encoded Ed25519 public key
+// matching signer, so other processes (or the browser, for debugging)
+// can verify /error redirects independently of this one.
+func webErrorPubKeyHandler(signer crypto.Signer) (http.HandlerFunc, error) {
+	pub, err := webErrorPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(pub)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(encoded))
+	}, nil
+}
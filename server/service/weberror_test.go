@@ -0,0 +1,102 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderWebErrorRoundTripsThroughVerify(t *testing.T) {
+	signer := NewDeterministicWebErrorSigner()
+
+	var redirectedTo string
+	render := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RenderWebError(w, r, signer, http.StatusFound, url.Values{"message": []string{"boom"}})
+	}))
+	defer render.Close()
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		redirectedTo = req.URL.String()
+		return http.ErrUseLastResponse
+	}}
+	_, err := client.Get(render.URL)
+	require.NoError(t, err)
+	require.NotEmpty(t, redirectedTo)
+
+	redirectedURL, err := url.Parse(redirectedTo)
+	require.NoError(t, err)
+
+	verified, err := VerifyWebErrorSignature(signer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, redirectedURL.RequestURI(), nil)
+	rec := httptest.NewRecorder()
+	verified.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestVerifyWebErrorSignatureRejectsTamperedParams(t *testing.T) {
+	signer := NewDeterministicWebErrorSigner()
+
+	params := url.Values{"message": []string{"boom"}}
+	sig, err := signWebErrorParams(signer, params)
+	require.NoError(t, err)
+
+	tampered := url.Values{"message": []string{"tampered"}, webErrorSigParam: []string{sig}}
+
+	verified, err := VerifyWebErrorSignature(signer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, webErrorPath+"?"+tampered.Encode(), nil)
+	rec := httptest.NewRecorder()
+	verified.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestVerifyWebErrorSignatureRejectsMissingSignature(t *testing.T) {
+	signer := NewDeterministicWebErrorSigner()
+
+	verified, err := VerifyWebErrorSignature(signer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, webErrorPath+"?message=boom", nil)
+	rec := httptest.NewRecorder()
+	verified.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestVerifyWebErrorSignatureIgnoresOtherPaths(t *testing.T) {
+	signer := NewDeterministicWebErrorSigner()
+
+	verified, err := VerifyWebErrorSignature(signer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/some/other/path", nil)
+	rec := httptest.NewRecorder()
+	verified.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestErrorsPubKeyHandlerServesPublicKey(t *testing.T) {
+	svc := &Service{webErrorSigner: NewDeterministicWebErrorSigner()}
+	handler, err := svc.ErrorsPubKeyHandler()
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/fleet/errors/pubkey", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEmpty(t, rec.Body.String())
+}